@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+type clickhouseDriver struct{}
+
+func init() {
+	registerDriver(driverSpec{
+		kind:          DriverClickHouse,
+		aliases:       []string{"ch"},
+		sqlDriverName: "clickhouse",
+		newDriver:     func() DBDriver { return clickhouseDriver{} },
+		dsn:           clickhouseDSN,
+		scope:         scopeRules{useDefaultDatabase: true, fallbackDatabase: "default"},
+		checkDatabase: checkDatabaseQuery(`SELECT name FROM system.databases WHERE name = ?`, "database not found"),
+		dialect:       clickhouseDialect,
+		placeholders:  placeholderDollar,
+	})
+}
+
+func (clickhouseDriver) Kind() DriverKind { return DriverClickHouse }
+
+// ReadOnlyConnection implements readOnlyConnector: every query carries the
+// readonly=1 setting (see clickhouseDSN), and ClickHouse has no transactions.
+func (clickhouseDriver) ReadOnlyConnection() bool { return true }
+
+func (clickhouseDriver) ListDatabases(ctx context.Context, db *sql.DB) ([]map[string]any, error) {
+	return queryAll(ctx, db, `SELECT name, engine FROM system.databases ORDER BY name`)
+}
+
+func (clickhouseDriver) ListSchemas(ctx context.Context, db *sql.DB) ([]map[string]any, error) {
+	return []map[string]any{}, nil
+}
+
+func (clickhouseDriver) ListTables(ctx context.Context, db *sql.DB, scope TableScope) ([]map[string]any, error) {
+	return queryAll(ctx, db, `
+SELECT name AS table_name, engine, total_rows, total_bytes
+FROM system.tables
+WHERE database = ? AND NOT is_temporary
+ORDER BY name`, scope.Database)
+}
+
+// DescribeTable includes the key membership and on-disk size of each column,
+// which matter more than nullability for a columnar table.
+func (clickhouseDriver) DescribeTable(ctx context.Context, db *sql.DB, ref TableRef) ([]map[string]any, error) {
+	return queryAll(ctx, db, `
+SELECT
+  position AS ordinal_position,
+  name AS column_name,
+  type AS data_type,
+  default_kind,
+  default_expression,
+  is_in_partition_key,
+  is_in_sorting_key,
+  is_in_primary_key,
+  compression_codec,
+  data_compressed_bytes,
+  data_uncompressed_bytes,
+  comment
+FROM system.columns
+WHERE database = ? AND table = ?
+ORDER BY position`, ref.Database, ref.Table)
+}
+
+// ListIndexes reports the primary and sorting keys followed by the data
+// skipping indices.
+func (clickhouseDriver) ListIndexes(ctx context.Context, db *sql.DB, ref TableRef) ([]map[string]any, error) {
+	keys, err := queryAll(ctx, db, `
+SELECT primary_key, sorting_key
+FROM system.tables
+WHERE database = ? AND name = ?`, ref.Database, ref.Table)
+	if err != nil {
+		return nil, err
+	}
+	out := []map[string]any{}
+	for _, k := range keys {
+		for _, key := range []struct{ column, typ string }{{"primary_key", "PRIMARY KEY"}, {"sorting_key", "ORDER BY"}} {
+			if expr, _ := k[key.column].(string); expr != "" {
+				out = append(out, map[string]any{
+					"index_name":  key.column,
+					"index_type":  key.typ,
+					"expression":  expr,
+					"granularity": nil,
+				})
+			}
+		}
+	}
+	skipping, err := queryAll(ctx, db, `
+SELECT name AS index_name, type AS index_type, expr AS expression, granularity
+FROM system.data_skipping_indices
+WHERE database = ? AND table = ?
+ORDER BY name`, ref.Database, ref.Table)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, skipping...), nil
+}
+
+// TablePartitions summarizes the active parts of each partition.
+func (clickhouseDriver) TablePartitions(ctx context.Context, db *sql.DB, ref TableRef) ([]map[string]any, error) {
+	return queryAll(ctx, db, `
+SELECT
+  partition,
+  partition_id,
+  count() AS parts,
+  sum(rows) AS rows,
+  sum(bytes_on_disk) AS bytes_on_disk,
+  sum(data_compressed_bytes) AS data_compressed_bytes,
+  sum(data_uncompressed_bytes) AS data_uncompressed_bytes,
+  max(modification_time) AS last_modified
+FROM system.parts
+WHERE database = ? AND table = ? AND active
+GROUP BY partition, partition_id
+ORDER BY partition_id`, ref.Database, ref.Table)
+}
+
+func (clickhouseDriver) Explain(ctx context.Context, db queryer, query, format string, args ...any) ([]map[string]any, error) {
+	f := strings.ToLower(strings.TrimSpace(format))
+	switch f {
+	case "":
+		f = "plan"
+	case "plan", "pipeline", "estimate":
+	default:
+		return nil, fmt.Errorf("unsupported format: %s (clickhouse supports: plan, pipeline, estimate)", format)
+	}
+	return queryAll(ctx, db, "EXPLAIN "+strings.ToUpper(f)+" "+query, args...)
+}
+
+// PageQuery wraps a query in a subquery so later pages only transfer the
+// requested rows. Row order is only stable if the query has an ORDER BY.
+func (clickhouseDriver) PageQuery(query string, limit, offset int) (string, bool) {
+	switch leadingKeyword(DriverClickHouse, query) {
+	case "SELECT", "WITH":
+		return fmt.Sprintf("SELECT * FROM (\n%s\n) LIMIT %d OFFSET %d", query, limit, offset), true
+	default:
+		return "", false
+	}
+}
+
+// IsPageRewriteError matches MULTIPLE_EXPRESSIONS_FOR_ALIAS (179) and
+// AMBIGUOUS_COLUMN_NAME (352), raised when the wrapped query selects the same
+// column name twice.
+func (clickhouseDriver) IsPageRewriteError(err error) bool {
+	var ce *clickhouse.Exception
+	return errors.As(err, &ce) && (ce.Code == 179 || ce.Code == 352)
+}
+
+func quoteIdentClickHouse(s string) string {
+	return "`" + strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(s) + "`"
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// cockroachDriver is postgresDriver for CockroachDB, whose pg_catalog does
+// not describe partitions or defaults the way Postgres does and which has no
+// pg_cancel_backend.
+type cockroachDriver struct{ postgresDriver }
+
+// TablePartitions lists the table's partitions (which belong to an index and
+// carry zone configurations rather than being tables of their own).
+func (cockroachDriver) TablePartitions(ctx context.Context, db *sql.DB, ref TableRef) ([]map[string]any, error) {
+	return queryAll(ctx, db, "SHOW PARTITIONS FROM TABLE "+quoteIdentPG(ref.Schema)+"."+quoteIdentPG(ref.Table))
+}
+
+// GetDDL uses SHOW CREATE TABLE, which always includes indexes.
+func (cockroachDriver) GetDDL(ctx context.Context, db *sql.DB, ref TableRef, _ bool) (DDLResult, error) {
+	var name, createSQL string
+	err := db.QueryRowContext(ctx, "SHOW CREATE TABLE "+quoteIdentPG(ref.Schema)+"."+quoteIdentPG(ref.Table)).Scan(&name, &createSQL)
+	if err != nil {
+		return DDLResult{}, err
+	}
+	return DDLResult{
+		TableDDL:   storedStatement(createSQL),
+		DriverKind: string(DriverPostgres),
+	}, nil
+}
+
+func (cockroachDriver) Explain(ctx context.Context, db queryer, query, format string, args ...any) ([]map[string]any, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "text":
+		return queryAll(ctx, db, "EXPLAIN "+query, args...)
+	case "verbose":
+		return queryAll(ctx, db, "EXPLAIN (VERBOSE) "+query, args...)
+	case "opt":
+		return queryAll(ctx, db, "EXPLAIN (OPT) "+query, args...)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s (cockroachdb supports: text, verbose, opt)", format)
+	}
+}
+
+// SessionID is not needed: see CancelSession.
+func (cockroachDriver) SessionID(ctx context.Context, conn *sql.Conn) (int64, error) {
+	return 0, nil
+}
+
+// CancelSession does nothing. CockroachDB has no pg_cancel_backend, and its
+// session ids do not fit statementController's int64. When the query context
+// ends pgx sets a deadline on the socket and closes the connection (it sends
+// no cancel request as configured here); the server stops the statement once
+// it notices the connection is gone. statement_timeout bounds it otherwise.
+func (cockroachDriver) CancelSession(ctx context.Context, db *sql.DB, id int64) error {
+	return nil
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgservicefile"
+)
+
+// passwordCommandTimeout bounds a passwordCommand.
+const passwordCommandTimeout = 10 * time.Second
+
+// envRefRe matches ${NAME}; $${NAME} is an escaped literal ${NAME}.
+var envRefRe = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// resolveConfig expands environment variables in every string of cfg, applies
+// connection URLs and then resolves each connection's credentials. Errors name the field or file
+// involved but never include a resolved value.
+func resolveConfig(cfg *Config) error {
+	if err := expandEnvFields(reflect.ValueOf(cfg).Elem(), ""); err != nil {
+		return err
+	}
+	for i := range cfg.Connections {
+		c := &cfg.Connections[i]
+		if err := applyURL(c); err != nil {
+			return fmt.Errorf("connection %s: %w", c.Name, err)
+		}
+		if err := resolveCredentials(c); err != nil {
+			return fmt.Errorf("connection %s: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+// expandEnv replaces ${NAME} references in s. A variable that is not set is
+// an error; one set to the empty string is not.
+func expandEnv(s string) (string, error) {
+	var missing string
+	out := envRefRe.ReplaceAllStringFunc(s, func(ref string) string {
+		if strings.HasPrefix(ref, "$$") {
+			return ref[1:]
+		}
+		name := ref[2 : len(ref)-1]
+		v, ok := os.LookupEnv(name)
+		if !ok && missing == "" {
+			missing = name
+		}
+		return v
+	})
+	if missing != "" {
+		return "", fmt.Errorf("environment variable %s is not set", missing)
+	}
+	return out, nil
+}
+
+// expandEnvFields applies expandEnv to the strings reachable from v: string
+// fields, slices and map values. path is the JSON path used in errors.
+func expandEnvFields(v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.String:
+		s, err := expandEnv(v.String())
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		v.SetString(s)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			if err := expandEnvFields(v.Field(i), name); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := expandEnvFields(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			s, err := expandEnv(iter.Value().String())
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", path, iter.Key().String(), err)
+			}
+			v.SetMapIndex(iter.Key(), reflect.ValueOf(s).Convert(v.Type().Elem()))
+		}
+	}
+	return nil
+}
+
+// resolveCredentials fills in the password from passwordFile or
+// passwordCommand, then applies the driver's own credential sources.
+func resolveCredentials(c *ConnectionConfig) error {
+	set := 0
+	for _, s := range []string{c.Password, c.PasswordFile, c.PasswordCommand} {
+		if s != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return fmt.Errorf("set only one of password, passwordFile and passwordCommand")
+	}
+	switch {
+	case c.PasswordFile != "":
+		b, err := os.ReadFile(expandHome(c.PasswordFile))
+		if err != nil {
+			return fmt.Errorf("passwordFile: %w", err)
+		}
+		c.Password = strings.TrimRight(string(b), "\r\n")
+	case c.PasswordCommand != "":
+		pw, err := runPasswordCommand(c.PasswordCommand)
+		if err != nil {
+			return fmt.Errorf("passwordCommand: %w", err)
+		}
+		c.Password = pw
+	}
+
+	// An unknown driver is reported by newDBService.
+	if spec, err := lookupDriver(c.Driver); err == nil && spec.credentials != nil {
+		return spec.credentials(c)
+	}
+	return nil
+}
+
+// runPasswordCommand runs command through the shell and returns its output
+// without the trailing newline.
+func runPasswordCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), passwordCommandTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, truncateString(msg, 200))
+		}
+		return "", err
+	}
+	pw := strings.TrimRight(string(out), "\r\n")
+	if pw == "" {
+		return "", errors.New("command printed nothing")
+	}
+	return pw, nil
+}
+
+func truncateString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// expandHome replaces a leading ~/ with the user's home directory.
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// pgCredentials applies a connection service from PGSERVICEFILE (default
+// ~/.pg_service.conf): its settings fill the fields left empty, and other
+// keywords are added to params. An empty password is looked up in .pgpass
+// (PGPASSFILE) by the driver on every connect.
+func pgCredentials(c *ConnectionConfig) error {
+	if c.Service == "" {
+		return nil
+	}
+	path := os.Getenv("PGSERVICEFILE")
+	if path == "" {
+		path = "~/.pg_service.conf"
+	}
+	sf, err := pgservicefile.ReadServicefile(expandHome(path))
+	if err != nil {
+		return fmt.Errorf("service %s: %w", c.Service, err)
+	}
+	svc, err := sf.GetService(c.Service)
+	if err != nil {
+		return fmt.Errorf("service %s: not found in %s", c.Service, path)
+	}
+	for k, v := range svc.Settings {
+		switch k {
+		case "host":
+			setIfEmpty(&c.Host, v)
+		case "port":
+			if c.Port == 0 {
+				port, err := strconv.Atoi(v)
+				if err != nil {
+					return fmt.Errorf("service %s: invalid port %q", c.Service, v)
+				}
+				c.Port = port
+			}
+		case "user":
+			setIfEmpty(&c.Username, v)
+		case "password":
+			setIfEmpty(&c.Password, v)
+		case "dbname":
+			setIfEmpty(&c.Database, v)
+		case "sslmode":
+			setIfEmpty(&c.SSLMode, v)
+		default:
+			if _, ok := c.Params[k]; !ok {
+				if c.Params == nil {
+					c.Params = map[string]string{}
+				}
+				c.Params[k] = v
+			}
+		}
+	}
+	return nil
+}
+
+// mysqlCredentials reads the [client] group of a MySQL option file into the
+// fields left empty: optionFile if set (it must exist), otherwise ~/.my.cnf
+// if present.
+func mysqlCredentials(c *ConnectionConfig) error {
+	path, required := c.OptionFile, true
+	if path == "" {
+		path, required = "~/.my.cnf", false
+	}
+	b, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		if !required && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("optionFile: %w", err)
+	}
+	opts, err := parseMyCnf(b, "client")
+	if err != nil {
+		return fmt.Errorf("optionFile %s: %w", path, err)
+	}
+	setIfEmpty(&c.Host, opts["host"])
+	setIfEmpty(&c.Username, opts["user"])
+	setIfEmpty(&c.Password, opts["password"])
+	setIfEmpty(&c.Database, opts["database"])
+	if p := opts["port"]; p != "" && c.Port == 0 {
+		port, err := strconv.Atoi(p)
+		if err != nil {
+			return fmt.Errorf("optionFile %s: invalid port %q", path, p)
+		}
+		c.Port = port
+	}
+	return nil
+}
+
+// parseMyCnf returns the options of group in a MySQL option file. Option
+// names use "_" and "-" interchangeably; values may be quoted. !include
+// directives are ignored.
+func parseMyCnf(b []byte, group string) (map[string]string, error) {
+	opts := map[string]string{}
+	in := false
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';' || line[0] == '!':
+			continue
+		case line[0] == '[':
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: malformed group", n)
+			}
+			in = strings.TrimSpace(line[1:len(line)-1]) == group
+			continue
+		case !in:
+			continue
+		}
+		key, value, _ := strings.Cut(line, "=")
+		key = strings.ReplaceAll(strings.TrimSpace(key), "-", "_")
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		opts[key] = value
+	}
+	return opts, sc.Err()
+}
+
+func setIfEmpty(dst *string, v string) {
+	if *dst == "" {
+		*dst = v
+	}
+}
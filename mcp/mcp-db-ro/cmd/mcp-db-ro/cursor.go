@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCursorTTL      = 5 * time.Minute
+	defaultMaxOpenCursors = 2
+)
+
+// serverCursorDriver is implemented by drivers that can page through a result
+// with a server-side cursor held open in the session's transaction.
+type serverCursorDriver interface {
+	DeclareCursor(ctx context.Context, q queryer, name, query string, args ...any) error
+	FetchCursor(ctx context.Context, q queryer, name string, n int) (resultSet, error)
+}
+
+// pageRewriter is implemented by drivers that can rewrite a query to return a
+// single page. ok is false if the query cannot be wrapped.
+type pageRewriter interface {
+	PageQuery(query string, limit, offset int) (rewritten string, ok bool)
+	// IsPageRewriteError reports whether err was caused by the rewrite itself
+	// (the original query must then be paged client-side).
+	IsPageRewriteError(err error) bool
+}
+
+// resultPager produces successive pages of one query result.
+type resultPager interface {
+	next(ctx context.Context, limit int) (page resultSet, more bool, err error)
+	readOnly() bool
+	close()
+}
+
+// newPager picks the paging strategy for query: a server-side cursor if the
+// driver supports one, otherwise re-running the query with an offset.
+func (c *dbClient) newPager(ctx context.Context, db *sql.DB, query string, args []any) (resultPager, error) {
+	if drv, ok := c.driver.(serverCursorDriver); ok {
+		switch leadingKeyword(c.driver.Kind(), query) {
+		case "SELECT", "WITH", "VALUES", "TABLE":
+			s, err := c.openReadOnly(ctx, db, c.cfg.queryTimeout())
+			if err != nil {
+				return nil, err
+			}
+			if s.inTx {
+				p := &serverCursorPager{s: s, drv: drv, name: "mcp_cursor_" + randomHex(8)}
+				err := s.run(ctx, func(ctx context.Context, q queryer) error {
+					return drv.DeclareCursor(ctx, q, p.name, trimStatement(c.driver.Kind(), query), args...)
+				})
+				if err != nil {
+					s.close()
+					return nil, err
+				}
+				return p, nil
+			}
+			// Cursors need a transaction; fall back to offset paging.
+			s.close()
+		}
+	}
+	p := &offsetPager{c: c, db: db, query: query, args: args}
+	if rw, ok := c.driver.(pageRewriter); ok {
+		p.rewriter = rw
+	}
+	return p, nil
+}
+
+type serverCursorPager struct {
+	s       *roSession
+	drv     serverCursorDriver
+	name    string
+	columns []resultColumn
+	pending [][]any
+}
+
+func (p *serverCursorPager) next(ctx context.Context, limit int) (resultSet, bool, error) {
+	// Fetch one row beyond the page to learn whether more rows exist; it is
+	// kept for the next page.
+	want := limit + 1 - len(p.pending)
+	if want > 0 {
+		var fetched resultSet
+		err := p.s.run(ctx, func(ctx context.Context, q queryer) error {
+			var err error
+			fetched, err = p.drv.FetchCursor(ctx, q, p.name, want)
+			return err
+		})
+		if err != nil {
+			return resultSet{}, false, err
+		}
+		p.columns = fetched.Columns
+		p.pending = append(p.pending, fetched.Rows...)
+	}
+	page := resultSet{Columns: p.columns, Rows: p.pending}
+	if len(page.Rows) > limit {
+		p.pending = append([][]any(nil), page.Rows[limit:]...)
+		page.Rows = page.Rows[:limit]
+		return page, true, nil
+	}
+	p.pending = nil
+	return page, false, nil
+}
+
+func (p *serverCursorPager) readOnly() bool { return p.s.inTx }
+
+func (p *serverCursorPager) close() { p.s.close() }
+
+// offsetPager re-runs the query for every page. Pages after the first are
+// rewritten to LIMIT/OFFSET when the driver supports it; otherwise the rows
+// before the offset are skipped client-side.
+type offsetPager struct {
+	c        *dbClient
+	db       *sql.DB
+	query    string
+	args     []any
+	rewriter pageRewriter
+	offset   int
+	inTx     bool
+}
+
+func (p *offsetPager) next(ctx context.Context, limit int) (resultSet, bool, error) {
+	var rows resultSet
+	var more bool
+	inTx, err := p.c.readOnly(ctx, p.db, func(ctx context.Context, q queryer) error {
+		if p.offset > 0 && p.rewriter != nil {
+			if rewritten, ok := p.rewriter.PageQuery(trimStatement(p.c.driver.Kind(), p.query), limit+1, p.offset); ok {
+				var err error
+				rows, _, err = queryPage(ctx, q, p.c.encoder(), rewritten, 0, 0, p.args...)
+				if err == nil {
+					if len(rows.Rows) > limit {
+						rows.Rows, more = rows.Rows[:limit], true
+					}
+					return nil
+				}
+				if !p.rewriter.IsPageRewriteError(err) {
+					return err
+				}
+				p.rewriter = nil
+			}
+		}
+		var err error
+		rows, more, err = queryPage(ctx, q, p.c.encoder(), p.query, p.offset, limit, p.args...)
+		return err
+	})
+	if err != nil {
+		return resultSet{}, false, err
+	}
+	p.inTx = inTx
+	p.offset += len(rows.Rows)
+	return rows, more, nil
+}
+
+func (p *offsetPager) readOnly() bool { return p.inTx }
+
+func (p *offsetPager) close() {}
+
+// queryCursor is an open, resumable db.query result.
+type queryCursor struct {
+	id string
+
+	mu       sync.Mutex // serializes fetches; guards the fields below
+	pager    resultPager
+	rowsRead int
+	closed   bool
+
+	timer *time.Timer // guarded by cursorStore.mu
+}
+
+// cursorStore holds a connection's open cursors. Cursors expire after ttl
+// without use; when max are open the least recently used one is closed.
+type cursorStore struct {
+	ttl time.Duration
+	max int
+
+	mu      sync.Mutex
+	cursors map[string]*queryCursor
+	order   []string // least recently used first
+}
+
+func newCursorStore(cfg ConnectionConfig) *cursorStore {
+	s := &cursorStore{
+		ttl:     defaultCursorTTL,
+		max:     defaultMaxOpenCursors,
+		cursors: map[string]*queryCursor{},
+	}
+	if cfg.CursorTTLSeconds > 0 {
+		s.ttl = time.Duration(cfg.CursorTTLSeconds) * time.Second
+	}
+	if cfg.MaxOpenCursors > 0 {
+		s.max = cfg.MaxOpenCursors
+	}
+	return s
+}
+
+// add registers pager as a new cursor and returns its id and expiry time.
+func (s *cursorStore) add(pager resultPager, rowsRead int) (string, string) {
+	cur := &queryCursor{id: randomHex(16), pager: pager, rowsRead: rowsRead}
+
+	s.mu.Lock()
+	var evicted []*queryCursor
+	for len(s.order) >= s.max {
+		evicted = append(evicted, s.removeLocked(s.order[0]))
+	}
+	s.cursors[cur.id] = cur
+	s.order = append(s.order, cur.id)
+	cur.timer = time.AfterFunc(s.ttl, func() { s.expire(cur.id) })
+	s.mu.Unlock()
+
+	for _, e := range evicted {
+		e.close()
+	}
+	return cur.id, time.Now().Add(s.ttl).UTC().Format(time.RFC3339)
+}
+
+// next fetches the next page of cursor id. The cursor is closed once the
+// result is exhausted or on error.
+func (s *cursorStore) next(ctx context.Context, id string, limit int, objects bool) (queryResult, error) {
+	s.mu.Lock()
+	cur, ok := s.cursors[id]
+	s.mu.Unlock()
+	if !ok {
+		return queryResult{}, fmt.Errorf("unknown or expired cursor")
+	}
+
+	cur.mu.Lock()
+	defer cur.mu.Unlock()
+	if cur.closed {
+		return queryResult{}, fmt.Errorf("unknown or expired cursor")
+	}
+	page, more, err := cur.pager.next(ctx, limit)
+	if err != nil {
+		s.drop(id)
+		cur.closeLocked()
+		return queryResult{}, err
+	}
+	cur.rowsRead += len(page.Rows)
+	res := newQueryResult(page, objects)
+	res.ReadOnlyTransaction = cur.pager.readOnly()
+	res.Truncated = more
+	res.RowsRead = cur.rowsRead
+	if !more {
+		s.drop(id)
+		cur.closeLocked()
+		return res, nil
+	}
+	if res.CursorExpiresAt = s.touch(id); res.CursorExpiresAt != "" {
+		res.NextCursor = id
+	}
+	return res, nil
+}
+
+// touch marks id as most recently used and restarts its TTL. It returns the
+// new expiry, or "" if the cursor was evicted in the meantime.
+func (s *cursorStore) touch(id string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur, ok := s.cursors[id]
+	if !ok {
+		return ""
+	}
+	cur.timer.Reset(s.ttl)
+	s.removeOrderLocked(id)
+	s.order = append(s.order, id)
+	return time.Now().Add(s.ttl).UTC().Format(time.RFC3339)
+}
+
+func (s *cursorStore) expire(id string) {
+	s.mu.Lock()
+	cur := s.removeLocked(id)
+	s.mu.Unlock()
+	if cur != nil {
+		cur.close()
+	}
+}
+
+func (s *cursorStore) drop(id string) {
+	s.mu.Lock()
+	s.removeLocked(id)
+	s.mu.Unlock()
+}
+
+// uses reports whether a cursor pages through db by re-running its query.
+// Cursors holding a server-side cursor keep a connection of db in use.
+func (s *cursorStore) uses(db *sql.DB) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, cur := range s.cursors {
+		if p, ok := cur.pager.(*offsetPager); ok && p.db == db {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *cursorStore) closeAll() {
+	s.mu.Lock()
+	cursors := make([]*queryCursor, 0, len(s.cursors))
+	for id := range s.cursors {
+		cursors = append(cursors, s.removeLocked(id))
+	}
+	s.mu.Unlock()
+	for _, cur := range cursors {
+		cur.close()
+	}
+}
+
+func (s *cursorStore) removeLocked(id string) *queryCursor {
+	cur, ok := s.cursors[id]
+	if !ok {
+		return nil
+	}
+	delete(s.cursors, id)
+	s.removeOrderLocked(id)
+	cur.timer.Stop()
+	return cur
+}
+
+func (s *cursorStore) removeOrderLocked(id string) {
+	for i, v := range s.order {
+		if v == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			return
+		}
+	}
+}
+
+func (cur *queryCursor) close() {
+	cur.mu.Lock()
+	defer cur.mu.Unlock()
+	cur.closeLocked()
+}
+
+func (cur *queryCursor) closeLocked() {
+	if !cur.closed {
+		cur.closed = true
+		cur.pager.close()
+	}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
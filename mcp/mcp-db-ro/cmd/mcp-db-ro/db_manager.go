@@ -12,97 +12,308 @@ import (
 )
 
 type dbService struct {
-	logger      *log.Logger
+	logger *log.Logger
+
+	// mu guards connections and export, which a config reload replaces, and
+	// the epoch of tool calls using them.
+	mu          sync.RWMutex
 	connections map[string]*dbClient
+	export      ExportConfig
+	epoch       *callEpoch
+
+	pools *poolLimiter
 }
 
 func newDBService(logger *log.Logger, cfg Config) (*dbService, error) {
+	if err := checkConnectionNames(cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.Pools.check(cfg); err != nil {
+		return nil, err
+	}
+	pools := newPoolLimiter(cfg.Pools)
 	connections := make(map[string]*dbClient, len(cfg.Connections))
 	for _, c := range cfg.Connections {
-		if strings.TrimSpace(c.Name) == "" {
-			return nil, fmt.Errorf("connection name required")
-		}
-		if _, ok := connections[c.Name]; ok {
-			return nil, fmt.Errorf("duplicate connection name: %s", c.Name)
-		}
-		kind, sqlDriverName, err := normalizeDriver(c.Driver)
+		client, err := newClient(logger, pools, c)
 		if err != nil {
+			for _, opened := range connections {
+				opened.close()
+			}
 			return nil, fmt.Errorf("connection %s: %w", c.Name, err)
 		}
-		driver, err := newDriver(kind)
-		if err != nil {
-			return nil, fmt.Errorf("connection %s: %w", c.Name, err)
-		}
-		c.Driver = string(kind)
+		connections[c.Name] = client
+	}
 
-		dsn, err := buildDSN(kind, c)
-		if err != nil {
-			return nil, fmt.Errorf("connection %s: %w", c.Name, err)
-		}
+	return &dbService{
+		logger:      logger,
+		connections: connections,
+		export:      cfg.Export,
+		epoch:       &callEpoch{},
+		pools:       pools,
+	}, nil
+}
 
-		db, err := sql.Open(sqlDriverName, dsn)
-		if err != nil {
-			return nil, fmt.Errorf("connection %s: open: %w", c.Name, err)
+// checkConnectionNames rejects empty and duplicate connection names.
+func checkConnectionNames(cfg Config) error {
+	seen := make(map[string]bool, len(cfg.Connections))
+	for _, c := range cfg.Connections {
+		if strings.TrimSpace(c.Name) == "" {
+			return fmt.Errorf("connection name required")
 		}
-		db.SetMaxOpenConns(4)
-		db.SetMaxIdleConns(4)
-		db.SetConnMaxLifetime(30 * time.Minute)
+		if seen[c.Name] {
+			return fmt.Errorf("duplicate connection name: %s", c.Name)
+		}
+		seen[c.Name] = true
+	}
+	return nil
+}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		err = db.PingContext(ctx)
-		cancel()
-		if err != nil {
-			_ = db.Close()
-			return nil, fmt.Errorf("connection %s: ping: %w", c.Name, err)
+// newClient sets up the connection described by c without connecting: pools
+// and tunnels connect on first use, see connHealth. Errors are configuration
+// errors. The client counts towards the limits of pools until it is closed.
+func newClient(logger *log.Logger, pools *poolLimiter, c ConnectionConfig) (*dbClient, error) {
+	spec, err := lookupDriver(c.Driver)
+	if err != nil {
+		return nil, err
+	}
+	c.Driver = string(spec.kind)
+	if c.SSH.enabled() && spec.openWith == nil {
+		return nil, fmt.Errorf("ssh is not supported for %s connections", spec.kind)
+	}
+	if c.TLS.custom() && spec.openWith == nil {
+		return nil, fmt.Errorf("tls settings are not supported for %s connections (use params)", spec.kind)
+	}
+	if err := c.Pool.validate(); err != nil {
+		return nil, err
+	}
+	if spec.newDocStore != nil {
+		return newDocClient(logger, pools, spec, c)
+	}
+	driver := spec.newDriver()
+
+	var tlsSt *tlsState
+	if c.TLS.custom() {
+		if tlsSt, err = loadTLS(c.TLS); err != nil {
+			return nil, err
+		}
+	}
+	var tunnel *sshTunnel
+	if c.SSH.enabled() {
+		if tunnel, err = newSSHTunnel(logger, c.Name, c.SSH); err != nil {
+			return nil, err
+		}
+	}
+	db, err := openDB(spec, driver, c, tunnel, tlsSt)
+	if err != nil {
+		if tunnel != nil {
+			tunnel.close()
 		}
+		return nil, err
+	}
 
-		connections[c.Name] = &dbClient{
-			cfg:           c,
-			db:            db,
-			driver:        driver,
-			sqlDriverName: sqlDriverName,
-			mu:            sync.RWMutex{},
-			dbByDatabase:  map[string]*sql.DB{},
-			selectedDB:    "",
-			bootstrapPing: true,
+	client := &dbClient{
+		cfg:          c,
+		logger:       logger,
+		spec:         spec,
+		db:           db,
+		driver:       driver,
+		tunnel:       tunnel,
+		tls:          tlsSt,
+		pools:        pools,
+		cursors:      newCursorStore(c),
+		mu:           sync.RWMutex{},
+		dbByDatabase: map[string]*dbPool{},
+		selectedDB:   map[string]string{},
+	}
+	pools.register(client)
+	return client, nil
+}
+
+// openDB opens a pool for cfg: through the driver's openWith when it is
+// tunneled or has TLS settings, else through its OpenDB if it has one, and
+// sizes it following cfg.Pool.
+func openDB(spec *driverSpec, driver DBDriver, cfg ConnectionConfig, tunnel *sshTunnel, tls *tlsState) (*sql.DB, error) {
+	dsn, err := spec.dsn(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var db *sql.DB
+	if tunnel != nil || tls != nil {
+		var dial dialFunc
+		if tunnel != nil {
+			dial = tunnel.dial
 		}
+		db, err = spec.openWith(cfg, dsn, dial, tls)
+	} else if opener, ok := driver.(dbOpener); ok {
+		db, err = opener.OpenDB(cfg, dsn)
+	} else {
+		db, err = sql.Open(spec.sqlDriverName, dsn)
 	}
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	cfg.Pool.apply(db)
+	return db, nil
+}
 
-	return &dbService{
-		logger:      logger,
-		connections: connections,
-	}, nil
+// newDocClient sets up a document database client; like SQL pools it
+// connects on first use.
+func newDocClient(logger *log.Logger, pools *poolLimiter, spec *driverSpec, cfg ConnectionConfig) (*dbClient, error) {
+	store, err := spec.newDocStore(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	client := &dbClient{
+		cfg:          cfg,
+		logger:       logger,
+		spec:         spec,
+		docs:         store,
+		pools:        pools,
+		cursors:      newCursorStore(cfg),
+		selectedDB:   map[string]string{},
+		dbByDatabase: map[string]*dbPool{},
+	}
+	pools.register(client)
+	return client, nil
 }
 
 func (s *dbService) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	for _, c := range s.connections {
-		_ = c.db.Close()
-		c.mu.Lock()
-		for _, db := range c.dbByDatabase {
-			_ = db.Close()
-		}
-		c.dbByDatabase = map[string]*sql.DB{}
-		c.mu.Unlock()
+		c.close()
+	}
+}
+
+// close closes the client's cursors and pools. database/sql lets queries
+// already running finish first.
+func (c *dbClient) close() {
+	defer c.pools.unregister(c)
+	c.cursors.closeAll()
+	if c.docs != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_ = c.docs.Close(ctx)
+		cancel()
+		return
+	}
+	_ = c.db.Close()
+	c.mu.Lock()
+	for _, p := range c.dbByDatabase {
+		_ = p.db.Close()
+	}
+	c.dbByDatabase = map[string]*dbPool{}
+	c.mu.Unlock()
+	if c.tunnel != nil {
+		c.tunnel.close()
+	}
+}
+
+// exportConfig returns the current export settings.
+func (s *dbService) exportConfig() ExportConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.export
+}
+
+// getClient returns the named connection once it is reachable.
+func (s *dbService) getClient(ctx context.Context, name string) (*dbClient, error) {
+	c, err := s.lookupClient(name)
+	if err != nil {
+		return nil, err
 	}
+	return c, c.ready(ctx)
 }
 
-func (s *dbService) getClient(name string) (*dbClient, error) {
+func (s *dbService) lookupClient(name string) (*dbClient, error) {
 	name = strings.TrimSpace(name)
 	if name == "" {
 		return nil, fmt.Errorf("connection is required")
 	}
+	s.mu.RLock()
 	c, ok := s.connections[name]
+	s.mu.RUnlock()
 	if !ok {
 		return nil, fmt.Errorf("unknown connection: %s", name)
 	}
 	return c, nil
 }
 
-func (s *dbService) listConnections() []string {
-	out := make([]string, 0, len(s.connections))
+// getSQLClient is getClient for tools that only apply to SQL connections.
+func (s *dbService) getSQLClient(ctx context.Context, name, tool string) (*dbClient, error) {
+	c, err := s.lookupClient(name)
+	if err != nil {
+		return nil, err
+	}
+	if c.docs != nil {
+		return nil, fmt.Errorf("%s is not available for %s connections (use db.find or db.aggregate)", tool, c.cfg.Driver)
+	}
+	return c, c.ready(ctx)
+}
+
+// getDocClient is getClient for tools that only apply to document databases.
+func (s *dbService) getDocClient(ctx context.Context, name, tool string) (*dbClient, error) {
+	c, err := s.lookupClient(name)
+	if err != nil {
+		return nil, err
+	}
+	if c.docs == nil {
+		return nil, fmt.Errorf("%s is only available for mongodb connections (use db.query)", tool)
+	}
+	return c, c.ready(ctx)
+}
+
+// observe records the outcome of a tool call on the named connection, see
+// dbClient.observe.
+func (s *dbService) observe(name string, err error) {
+	if c, lerr := s.lookupClient(name); lerr == nil {
+		c.observe(err)
+	}
+}
+
+// detectFlavor returns the server flavor and the driver variant for it. If
+// detection fails the base driver is kept.
+func detectFlavor(ctx context.Context, logger *log.Logger, name string, db *sql.DB, driver DBDriver) (Flavor, DBDriver) {
+	detector, ok := driver.(flavorDetector)
+	if !ok {
+		return "", driver
+	}
+	flavor, err := detector.DetectFlavor(ctx, db)
+	if err != nil {
+		if logger != nil {
+			logger.Printf("connection %s: flavor detection failed, assuming %s: %v", name, driver.Kind(), err)
+		}
+		return "", driver
+	}
+	return flavor, flavorDriver(driver, flavor)
+}
+
+// listConnections returns the configured connections with their driver and
+// status and, for postgres and mysql, the detected server flavor, SSH tunnel
+// state and certificate expiry.
+func (s *dbService) listConnections() []map[string]any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.connections))
 	for k := range s.connections {
-		out = append(out, k)
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	out := make([]map[string]any, 0, len(names))
+	for _, name := range names {
+		c := s.connections[name]
+		row := map[string]any{"name": name, "driver": c.cfg.Driver, "status": c.healthStatus()}
+		c.health.mu.Lock()
+		if c.flavor != "" {
+			row["flavor"] = string(c.flavor)
+		}
+		c.health.mu.Unlock()
+		if c.tunnel != nil {
+			row["ssh"] = c.tunnel.status()
+		}
+		if c.tls != nil {
+			row["tls"] = c.tls.status()
+		}
+		out = append(out, row)
 	}
-	sort.Strings(out)
 	return out
 }
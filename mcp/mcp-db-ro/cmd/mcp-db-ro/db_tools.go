@@ -4,119 +4,139 @@ import (
 	"context"
 	"fmt"
 	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
-func (s *dbService) listDatabases(conn string) (any, error) {
-	c, err := s.getClient(conn)
+func (s *dbService) listDatabases(ctx context.Context, conn string) (any, error) {
+	c, err := s.getClient(ctx, conn)
 	if err != nil {
 		return nil, err
 	}
-	return c.driver.ListDatabases(context.Background(), c.db)
+	ctx, cancel := c.withQueryTimeout(ctx)
+	defer cancel()
+	if c.docs != nil {
+		return c.docs.ListDatabases(ctx)
+	}
+	return c.driver.ListDatabases(ctx, c.db)
 }
 
-func (s *dbService) listSchemas(conn, database string) (any, error) {
-	c, err := s.getClient(conn)
+func (s *dbService) listSchemas(ctx context.Context, conn, database string) (any, error) {
+	c, err := s.getClient(ctx, conn)
 	if err != nil {
 		return nil, err
 	}
-	scope, err := c.normalizeScope(TableScope{Database: database})
+	if c.docs != nil {
+		return []map[string]any{}, nil
+	}
+	ctx, cancel := c.withQueryTimeout(ctx)
+	defer cancel()
+	scope, err := c.normalizeScope(ctx, TableScope{Database: database})
 	if err != nil {
 		return nil, err
 	}
-	db, err := c.dbForDatabase(context.Background(), scope.Database)
+	db, err := c.dbForDatabase(ctx, scope.Database)
 	if err != nil {
 		return nil, err
 	}
-	return c.driver.ListSchemas(context.Background(), db)
+	return c.driver.ListSchemas(ctx, db)
 }
 
-func (s *dbService) listTables(conn, database, schema string) (any, error) {
-	c, err := s.getClient(conn)
+func (s *dbService) listTables(ctx context.Context, conn, database, schema string) (any, error) {
+	c, err := s.getClient(ctx, conn)
 	if err != nil {
 		return nil, err
 	}
-	scope, err := c.normalizeScope(TableScope{Database: database, Schema: schema})
+	ctx, cancel := c.withQueryTimeout(ctx)
+	defer cancel()
+	if c.docs != nil {
+		database, err := c.requireDatabase(ctx, database)
+		if err != nil {
+			return nil, err
+		}
+		return c.docs.ListCollections(ctx, database)
+	}
+	scope, err := c.normalizeScope(ctx, TableScope{Database: database, Schema: schema})
 	if err != nil {
 		return nil, err
 	}
-	db, err := c.dbForDatabase(context.Background(), scope.Database)
+	db, err := c.dbForDatabase(ctx, scope.Database)
 	if err != nil {
 		return nil, err
 	}
-	return c.driver.ListTables(context.Background(), db, scope)
+	return c.driver.ListTables(ctx, db, scope)
 }
 
-func (s *dbService) describeTable(conn, database, schema, table string) (any, error) {
-	c, err := s.getClient(conn)
+func (s *dbService) describeTable(ctx context.Context, conn, database, schema, table string, sampleSize int) (any, error) {
+	c, err := s.getClient(ctx, conn)
 	if err != nil {
 		return nil, err
 	}
-	if c.driver.Kind() == DriverMySQL {
-		dbName, err := c.requireDatabase(database)
+	ctx, cancel := c.withQueryTimeout(ctx)
+	defer cancel()
+	if c.docs != nil {
+		database, err := c.requireDatabase(ctx, database)
 		if err != nil {
 			return nil, err
 		}
-		database = dbName
+		return c.docs.DescribeCollection(ctx, database, table, sampleSize)
 	}
-	ref, err := c.normalizeRef(TableRef{Database: database, Schema: schema, Table: table})
+	ref, err := c.normalizeRef(ctx, TableRef{Database: database, Schema: schema, Table: table})
 	if err != nil {
 		return nil, err
 	}
-	db, err := c.dbForDatabase(context.Background(), ref.Database)
+	db, err := c.dbForDatabase(ctx, ref.Database)
 	if err != nil {
 		return nil, err
 	}
-	return c.driver.DescribeTable(context.Background(), db, ref)
+	return c.driver.DescribeTable(ctx, db, ref)
 }
 
-func (s *dbService) listIndexes(conn, database, schema, table string) (any, error) {
-	c, err := s.getClient(conn)
+func (s *dbService) listIndexes(ctx context.Context, conn, database, schema, table string) (any, error) {
+	c, err := s.getClient(ctx, conn)
 	if err != nil {
 		return nil, err
 	}
-	if c.driver.Kind() == DriverMySQL {
-		dbName, err := c.requireDatabase(database)
+	ctx, cancel := c.withQueryTimeout(ctx)
+	defer cancel()
+	if c.docs != nil {
+		database, err := c.requireDatabase(ctx, database)
 		if err != nil {
 			return nil, err
 		}
-		database = dbName
+		return c.docs.ListIndexes(ctx, database, table)
 	}
-	ref, err := c.normalizeRef(TableRef{Database: database, Schema: schema, Table: table})
+	ref, err := c.normalizeRef(ctx, TableRef{Database: database, Schema: schema, Table: table})
 	if err != nil {
 		return nil, err
 	}
-	db, err := c.dbForDatabase(context.Background(), ref.Database)
+	db, err := c.dbForDatabase(ctx, ref.Database)
 	if err != nil {
 		return nil, err
 	}
-	return c.driver.ListIndexes(context.Background(), db, ref)
+	return c.driver.ListIndexes(ctx, db, ref)
 }
 
-func (s *dbService) tablePartitions(conn, database, schema, table string) (any, error) {
-	c, err := s.getClient(conn)
+func (s *dbService) tablePartitions(ctx context.Context, conn, database, schema, table string) (any, error) {
+	c, err := s.getSQLClient(ctx, conn, "db.tablePartitions")
 	if err != nil {
 		return nil, err
 	}
-	if c.driver.Kind() == DriverMySQL {
-		dbName, err := c.requireDatabase(database)
-		if err != nil {
-			return nil, err
-		}
-		database = dbName
-	}
-	ref, err := c.normalizeRef(TableRef{Database: database, Schema: schema, Table: table})
+	ctx, cancel := c.withQueryTimeout(ctx)
+	defer cancel()
+	ref, err := c.normalizeRef(ctx, TableRef{Database: database, Schema: schema, Table: table})
 	if err != nil {
 		return nil, err
 	}
-	db, err := c.dbForDatabase(context.Background(), ref.Database)
+	db, err := c.dbForDatabase(ctx, ref.Database)
 	if err != nil {
 		return nil, err
 	}
-	return c.driver.TablePartitions(context.Background(), db, ref)
+	return c.driver.TablePartitions(ctx, db, ref)
 }
 
-func (s *dbService) explain(conn, database, query, format string) (any, error) {
-	c, err := s.getClient(conn)
+func (s *dbService) explain(ctx context.Context, conn, database, query, format string, params []queryParam) (any, error) {
+	c, err := s.getClient(ctx, conn)
 	if err != nil {
 		return nil, err
 	}
@@ -124,103 +144,196 @@ func (s *dbService) explain(conn, database, query, format string) (any, error) {
 	if query == "" {
 		return nil, fmt.Errorf("query is required")
 	}
-	if !isReadOnlySQL(query) {
-		return nil, fmt.Errorf("query blocked (only SELECT/WITH/SHOW/EXPLAIN allowed)")
+	if c.docs != nil {
+		return s.explainCommand(ctx, c, database, query, format)
 	}
-	scope, err := c.normalizeScope(TableScope{Database: database})
+	if err := checkReadOnlySQL(c.driver.Kind(), query); err != nil {
+		return nil, err
+	}
+	query, args, err := c.bindParams(query, params)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := c.normalizeScope(ctx, TableScope{Database: database})
 	if err != nil {
 		return nil, err
 	}
-	db, err := c.dbForDatabase(context.Background(), scope.Database)
+	db, err := c.dbForDatabase(ctx, scope.Database)
 	if err != nil {
 		return nil, err
 	}
-	return c.driver.Explain(context.Background(), db, query, format)
+	var plan []map[string]any
+	readOnly, err := c.readOnly(ctx, db, func(ctx context.Context, q queryer) error {
+		var err error
+		plan, err = c.driver.Explain(ctx, q, query, format, args...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return explainResult{Plan: plan, ReadOnlyTransaction: readOnly}, nil
 }
 
-func (s *dbService) query(conn, database, query string, limit int) (any, error) {
-	c, err := s.getClient(conn)
+func (s *dbService) query(ctx context.Context, conn, database, query string, params []queryParam, limit int, cursor string, objects bool) (any, error) {
+	c, err := s.getSQLClient(ctx, conn, "db.query")
 	if err != nil {
 		return nil, err
 	}
+	if limit <= 0 {
+		limit = 200
+	}
+	if cursor = strings.TrimSpace(cursor); cursor != "" {
+		return c.cursors.next(ctx, cursor, limit, objects)
+	}
 	query = strings.TrimSpace(query)
 	if query == "" {
 		return nil, fmt.Errorf("query is required")
 	}
-	if !isReadOnlySQL(query) {
-		return nil, fmt.Errorf("query blocked (only SELECT/WITH/SHOW/EXPLAIN allowed)")
+	if err := checkReadOnlySQL(c.driver.Kind(), query); err != nil {
+		return nil, err
 	}
-	if limit <= 0 {
-		limit = 200
+	query, args, err := c.bindParams(query, params)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := c.normalizeScope(ctx, TableScope{Database: database})
+	if err != nil {
+		return nil, err
+	}
+	db, err := c.dbForDatabase(ctx, scope.Database)
+	if err != nil {
+		return nil, err
 	}
-	scope, err := c.normalizeScope(TableScope{Database: database})
+	pager, err := c.newPager(ctx, db, query, args)
 	if err != nil {
 		return nil, err
 	}
-	db, err := c.dbForDatabase(context.Background(), scope.Database)
+	page, more, err := pager.next(ctx, limit)
 	if err != nil {
+		pager.close()
 		return nil, err
 	}
-	return queryAllLimited(context.Background(), db, query, limit)
+	res := newQueryResult(page, objects)
+	res.ReadOnlyTransaction = pager.readOnly()
+	res.Truncated = more
+	res.RowsRead = len(page.Rows)
+	if more {
+		res.NextCursor, res.CursorExpiresAt = c.cursors.add(pager, len(page.Rows))
+	} else {
+		pager.close()
+	}
+	return res, nil
 }
 
-func (s *dbService) getDDL(conn, database, schema, table string, includeIndexes bool) (any, error) {
-	c, err := s.getClient(conn)
+func (s *dbService) getDDL(ctx context.Context, conn, database, schema, table string, includeIndexes bool) (any, error) {
+	c, err := s.getSQLClient(ctx, conn, "db.getDDL")
 	if err != nil {
 		return nil, err
 	}
-	if c.driver.Kind() == DriverMySQL {
-		dbName, err := c.requireDatabase(database)
-		if err != nil {
-			return nil, err
-		}
-		database = dbName
-	}
-	ref, err := c.normalizeRef(TableRef{Database: database, Schema: schema, Table: table})
+	ctx, cancel := c.withQueryTimeout(ctx)
+	defer cancel()
+	ref, err := c.normalizeRef(ctx, TableRef{Database: database, Schema: schema, Table: table})
 	if err != nil {
 		return nil, err
 	}
-	db, err := c.dbForDatabase(context.Background(), ref.Database)
+	db, err := c.dbForDatabase(ctx, ref.Database)
 	if err != nil {
 		return nil, err
 	}
-	return c.driver.GetDDL(context.Background(), db, ref, includeIndexes)
+	return c.driver.GetDDL(ctx, db, ref, includeIndexes)
 }
 
-func (s *dbService) useDatabase(conn, database string) (any, error) {
-	c, err := s.getClient(conn)
+func (s *dbService) useDatabase(ctx context.Context, conn, database string) (any, error) {
+	c, err := s.getClient(ctx, conn)
 	if err != nil {
 		return nil, err
 	}
+	ctx, cancel := c.withQueryTimeout(ctx)
+	defer cancel()
 	database = strings.TrimSpace(database)
 	if database == "" {
 		return nil, fmt.Errorf("database is required")
 	}
 
-	switch c.driver.Kind() {
-	case DriverPostgres:
-		// Validate by opening/pinging (cached).
-		if _, err := c.dbForDatabase(context.Background(), database); err != nil {
-			return nil, err
-		}
-		c.setSelectedDatabase(database)
-	case DriverMySQL:
-		// Best-effort validation: check visibility in information_schema.
-		_, err := queryAll(context.Background(), c.db, `SELECT schema_name FROM information_schema.schemata WHERE schema_name = ? LIMIT 1`, database)
-		if err != nil {
-			// If permissions prevent access to information_schema, still allow selecting.
-			c.setSelectedDatabase(database)
-			return map[string]any{
-				"selectedDatabase": database,
-				"note":             "database selected; validation skipped due to error: " + err.Error(),
-			}, nil
-		}
-		c.setSelectedDatabase(database)
-	default:
+	if c.spec.checkDatabase == nil {
 		return nil, fmt.Errorf("unsupported driver: %s", c.cfg.Driver)
 	}
+	note, err := c.spec.checkDatabase(ctx, c, database)
+	if err != nil {
+		return nil, err
+	}
+	c.setSelectedDatabase(ctx, database)
 
-	return map[string]any{
+	out := map[string]any{
 		"selectedDatabase": database,
-	}, nil
+	}
+	if note != "" {
+		out["note"] = note
+	}
+	return out, nil
+}
+
+// explainCommand explains a MongoDB command document given as Extended JSON.
+func (s *dbService) explainCommand(ctx context.Context, c *dbClient, database, command, verbosity string) (any, error) {
+	cmd, err := parseMongoDoc("query", command)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMongoExplain(cmd); err != nil {
+		return nil, err
+	}
+	database, err = c.requireDatabase(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := c.withQueryTimeout(ctx)
+	defer cancel()
+	plan, err := c.docs.Explain(ctx, database, cmd, verbosity)
+	if err != nil {
+		return nil, err
+	}
+	return explainResult{Plan: []map[string]any{plan}}, nil
+}
+
+func (s *dbService) find(ctx context.Context, conn, database, collection string, q findQuery) (any, error) {
+	c, err := s.getDocClient(ctx, conn, "db.find")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMongoScripts(q.Filter); err != nil {
+		return nil, err
+	}
+	if err := checkMongoScripts(q.Projection); err != nil {
+		return nil, err
+	}
+	database, err = c.requireDatabase(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	if q.Limit <= 0 {
+		q.Limit = 200
+	}
+	ctx, cancel := c.withQueryTimeout(ctx)
+	defer cancel()
+	return c.docs.Find(ctx, database, collection, q)
+}
+
+func (s *dbService) aggregate(ctx context.Context, conn, database, collection string, pipeline []bson.D, limit int) (any, error) {
+	c, err := s.getDocClient(ctx, conn, "db.aggregate")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMongoPipeline(pipeline); err != nil {
+		return nil, err
+	}
+	database, err = c.requireDatabase(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = 200
+	}
+	ctx, cancel := c.withQueryTimeout(ctx)
+	defer cancel()
+	return c.docs.Aggregate(ctx, database, collection, pipeline, limit)
 }
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// GetDDL uses SHOW CREATE TABLE, which includes the engine, keys, skipping
+// indices and settings.
+func (clickhouseDriver) GetDDL(ctx context.Context, db *sql.DB, ref TableRef, _ bool) (DDLResult, error) {
+	qualified := quoteIdentClickHouse(ref.Database) + "." + quoteIdentClickHouse(ref.Table)
+	var createSQL string
+	err := db.QueryRowContext(ctx, "SHOW CREATE TABLE "+qualified).Scan(&createSQL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DDLResult{}, fmt.Errorf("table not found: %s", qualified)
+	}
+	if err != nil {
+		return DDLResult{}, err
+	}
+	createSQL = strings.TrimSpace(createSQL)
+	if !strings.HasSuffix(createSQL, ";") {
+		createSQL += ";"
+	}
+	return DDLResult{
+		TableDDL:   createSQL + "\n",
+		DriverKind: string(DriverClickHouse),
+	}, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// GetDDL returns the CREATE statement DuckDB keeps for the table or view
+// (constraints included) and, optionally, those of its indexes.
+func (duckdbDriver) GetDDL(ctx context.Context, db *sql.DB, ref TableRef, includeIndexes bool) (DDLResult, error) {
+	database, err := duckdbResolveTable(ctx, db, ref)
+	if err != nil {
+		return DDLResult{}, err
+	}
+
+	var tableSQL string
+	err = db.QueryRowContext(ctx, `
+SELECT sql FROM duckdb_tables() WHERE database_name = $1 AND schema_name = $2 AND table_name = $3
+UNION ALL
+SELECT sql FROM duckdb_views() WHERE database_name = $1 AND schema_name = $2 AND view_name = $3
+LIMIT 1`, database, ref.Schema, ref.Table).Scan(&tableSQL)
+	if err != nil {
+		return DDLResult{}, err
+	}
+	out := DDLResult{
+		TableDDL:   storedStatement(tableSQL),
+		DriverKind: string(DriverDuckDB),
+	}
+	if !includeIndexes {
+		return out, nil
+	}
+
+	rows, err := db.QueryContext(ctx, `
+SELECT sql FROM duckdb_indexes()
+WHERE database_name = $1 AND schema_name = $2 AND table_name = $3 AND sql IS NOT NULL
+ORDER BY index_name`, database, ref.Schema, ref.Table)
+	if err != nil {
+		return DDLResult{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return DDLResult{}, err
+		}
+		out.IndexDDLs = append(out.IndexDDLs, strings.TrimSuffix(storedStatement(s), "\n"))
+	}
+	if err := rows.Err(); err != nil {
+		return DDLResult{}, err
+	}
+	return out, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// GetDDL returns the CREATE statements SQLite stored in sqlite_master, which
+// are exactly what was executed (including comments and formatting).
+func (sqliteDriver) GetDDL(ctx context.Context, db *sql.DB, ref TableRef, includeIndexes bool) (DDLResult, error) {
+	master := quoteSQLiteIdent(ref.Database) + ".sqlite_master"
+
+	var tableSQL string
+	err := db.QueryRowContext(ctx, `SELECT sql FROM `+master+` WHERE type = 'table' AND name = ?`, ref.Table).Scan(&tableSQL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DDLResult{}, fmt.Errorf("table not found: %s.%s", ref.Database, ref.Table)
+	}
+	if err != nil {
+		return DDLResult{}, err
+	}
+	out := DDLResult{
+		TableDDL:   storedStatement(tableSQL),
+		DriverKind: string(DriverSQLite),
+	}
+	if !includeIndexes {
+		return out, nil
+	}
+
+	// Indexes backing PRIMARY KEY/UNIQUE constraints have no stored SQL; they
+	// are part of the table definition.
+	rows, err := db.QueryContext(ctx, `
+SELECT sql FROM `+master+`
+WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL
+ORDER BY name`, ref.Table)
+	if err != nil {
+		return DDLResult{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return DDLResult{}, err
+		}
+		out.IndexDDLs = append(out.IndexDDLs, strings.TrimSuffix(storedStatement(s), "\n"))
+	}
+	if err := rows.Err(); err != nil {
+		return DDLResult{}, err
+	}
+	return out, nil
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+func (sqlserverDriver) GetDDL(ctx context.Context, db *sql.DB, ref TableRef, includeIndexes bool) (DDLResult, error) {
+	var objectID sql.NullInt64
+	if err := db.QueryRowContext(ctx, `SELECT `+sqlserverObjectID, ref.Schema, ref.Table).Scan(&objectID); err != nil {
+		return DDLResult{}, err
+	}
+	if !objectID.Valid {
+		return DDLResult{}, fmt.Errorf("table not found: %s.%s", ref.Schema, ref.Table)
+	}
+	id := objectID.Int64
+	qualified := quoteIdentSQLServer(ref.Schema) + "." + quoteIdentSQLServer(ref.Table)
+
+	columns, err := sqlserverColumns(ctx, db, id)
+	if err != nil {
+		return DDLResult{}, err
+	}
+	constraints, err := sqlserverConstraints(ctx, db, id)
+	if err != nil {
+		return DDLResult{}, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s (\n", qualified)
+	for i, c := range columns {
+		if i > 0 {
+			b.WriteString(",\n")
+		}
+		b.WriteString("  ")
+		b.WriteString(quoteIdentSQLServer(c.Name))
+		if c.Computed != "" {
+			b.WriteString(" AS ")
+			b.WriteString(c.Computed)
+			if c.Persisted {
+				b.WriteString(" PERSISTED")
+			}
+			continue
+		}
+		b.WriteString(" ")
+		b.WriteString(c.Type)
+		if c.Identity != "" {
+			b.WriteString(" IDENTITY(")
+			b.WriteString(c.Identity)
+			b.WriteString(")")
+		}
+		if c.NotNull {
+			b.WriteString(" NOT NULL")
+		} else {
+			b.WriteString(" NULL")
+		}
+		if c.Default != "" {
+			b.WriteString(" CONSTRAINT ")
+			b.WriteString(quoteIdentSQLServer(c.DefaultName))
+			b.WriteString(" DEFAULT ")
+			b.WriteString(c.Default)
+		}
+	}
+	for _, con := range constraints {
+		b.WriteString(",\n  CONSTRAINT ")
+		b.WriteString(quoteIdentSQLServer(con.Name))
+		b.WriteString(" ")
+		b.WriteString(con.Def)
+	}
+	b.WriteString("\n);\n")
+
+	out := DDLResult{
+		TableDDL:   b.String(),
+		DriverKind: string(DriverSQLServer),
+	}
+
+	if includeIndexes {
+		indexes, notes, err := sqlserverIndexes(ctx, db, id, qualified)
+		if err != nil {
+			return DDLResult{}, err
+		}
+		out.IndexDDLs = indexes
+		out.Notes = notes
+	}
+
+	return out, nil
+}
+
+type sqlserverColumn struct {
+	Name        string
+	Type        string
+	NotNull     bool
+	Default     string
+	DefaultName string
+	Identity    string // "seed, increment"
+	Computed    string
+	Persisted   bool
+}
+
+func sqlserverColumns(ctx context.Context, db *sql.DB, objectID int64) ([]sqlserverColumn, error) {
+	rows, err := db.QueryContext(ctx, `
+SELECT
+  c.name,`+sqlserverTypeExpr+` AS data_type,
+  CAST(CASE WHEN c.is_nullable = 1 THEN 0 ELSE 1 END AS bit) AS not_null,
+  COALESCE(dc.definition, '') AS column_default,
+  COALESCE(dc.name, '') AS default_name,
+  COALESCE(CAST(idc.seed_value AS varchar(40)) + ', ' + CAST(idc.increment_value AS varchar(40)), '') AS identity_spec,
+  COALESCE(cc.definition, '') AS computed,
+  COALESCE(cc.is_persisted, 0) AS persisted
+FROM sys.columns c
+JOIN sys.types ty ON ty.user_type_id = c.user_type_id
+LEFT JOIN sys.default_constraints dc ON dc.object_id = c.default_object_id
+LEFT JOIN sys.identity_columns idc ON idc.object_id = c.object_id AND idc.column_id = c.column_id
+LEFT JOIN sys.computed_columns cc ON cc.object_id = c.object_id AND cc.column_id = c.column_id
+WHERE c.object_id = @p1
+ORDER BY c.column_id`, objectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []sqlserverColumn
+	for rows.Next() {
+		var c sqlserverColumn
+		if err := rows.Scan(&c.Name, &c.Type, &c.NotNull, &c.Default, &c.DefaultName, &c.Identity, &c.Computed, &c.Persisted); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type sqlserverConstraint struct {
+	Name string
+	Def  string
+}
+
+// sqlserverConstraints returns primary key, unique, foreign key and check
+// constraints, in that order.
+func sqlserverConstraints(ctx context.Context, db *sql.DB, objectID int64) ([]sqlserverConstraint, error) {
+	keys, err := sqlserverKeyConstraints(ctx, db, objectID)
+	if err != nil {
+		return nil, err
+	}
+	fks, err := sqlserverForeignKeys(ctx, db, objectID)
+	if err != nil {
+		return nil, err
+	}
+	checks, err := sqlserverCheckConstraints(ctx, db, objectID)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(keys, fks...), checks...), nil
+}
+
+func sqlserverKeyConstraints(ctx context.Context, db *sql.DB, objectID int64) ([]sqlserverConstraint, error) {
+	rows, err := db.QueryContext(ctx, `
+SELECT kc.name, kc.type, i.type_desc, c.name, ic.is_descending_key
+FROM sys.key_constraints kc
+JOIN sys.indexes i ON i.object_id = kc.parent_object_id AND i.index_id = kc.unique_index_id
+JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id AND ic.is_included_column = 0
+JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+WHERE kc.parent_object_id = @p1
+ORDER BY CASE kc.type WHEN 'PK' THEN 0 ELSE 1 END, kc.name, ic.key_ordinal`, objectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []sqlserverConstraint
+	var kinds []string
+	var cols [][]string
+	for rows.Next() {
+		var name, typ, indexType, col string
+		var desc bool
+		if err := rows.Scan(&name, &typ, &indexType, &col, &desc); err != nil {
+			return nil, err
+		}
+		if len(out) == 0 || out[len(out)-1].Name != name {
+			kind := "UNIQUE"
+			if strings.TrimSpace(typ) == "PK" {
+				kind = "PRIMARY KEY"
+			}
+			out = append(out, sqlserverConstraint{Name: name})
+			kinds = append(kinds, kind+" "+indexType)
+			cols = append(cols, nil)
+		}
+		cols[len(cols)-1] = append(cols[len(cols)-1], sqlserverIndexColumn(col, desc))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i].Def = fmt.Sprintf("%s (%s)", kinds[i], strings.Join(cols[i], ", "))
+	}
+	return out, nil
+}
+
+func sqlserverForeignKeys(ctx context.Context, db *sql.DB, objectID int64) ([]sqlserverConstraint, error) {
+	rows, err := db.QueryContext(ctx, `
+SELECT
+  fk.name,
+  pc.name AS column_name,
+  OBJECT_SCHEMA_NAME(fk.referenced_object_id) AS ref_schema,
+  OBJECT_NAME(fk.referenced_object_id) AS ref_table,
+  rc.name AS ref_column,
+  fk.delete_referential_action_desc,
+  fk.update_referential_action_desc
+FROM sys.foreign_keys fk
+JOIN sys.foreign_key_columns fkc ON fkc.constraint_object_id = fk.object_id
+JOIN sys.columns pc ON pc.object_id = fkc.parent_object_id AND pc.column_id = fkc.parent_column_id
+JOIN sys.columns rc ON rc.object_id = fkc.referenced_object_id AND rc.column_id = fkc.referenced_column_id
+WHERE fk.parent_object_id = @p1
+ORDER BY fk.name, fkc.constraint_column_id`, objectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type fkDef struct {
+		name, refTable, onDelete, onUpdate string
+		cols, refCols                      []string
+	}
+	var fks []*fkDef
+	for rows.Next() {
+		var name, col, refSchema, refTable, refCol, onDelete, onUpdate string
+		if err := rows.Scan(&name, &col, &refSchema, &refTable, &refCol, &onDelete, &onUpdate); err != nil {
+			return nil, err
+		}
+		if len(fks) == 0 || fks[len(fks)-1].name != name {
+			fks = append(fks, &fkDef{
+				name:     name,
+				refTable: quoteIdentSQLServer(refSchema) + "." + quoteIdentSQLServer(refTable),
+				onDelete: onDelete,
+				onUpdate: onUpdate,
+			})
+		}
+		fk := fks[len(fks)-1]
+		fk.cols = append(fk.cols, quoteIdentSQLServer(col))
+		fk.refCols = append(fk.refCols, quoteIdentSQLServer(refCol))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]sqlserverConstraint, 0, len(fks))
+	for _, fk := range fks {
+		def := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)",
+			strings.Join(fk.cols, ", "), fk.refTable, strings.Join(fk.refCols, ", "))
+		if fk.onDelete != "NO_ACTION" {
+			def += " ON DELETE " + strings.ReplaceAll(fk.onDelete, "_", " ")
+		}
+		if fk.onUpdate != "NO_ACTION" {
+			def += " ON UPDATE " + strings.ReplaceAll(fk.onUpdate, "_", " ")
+		}
+		out = append(out, sqlserverConstraint{Name: fk.name, Def: def})
+	}
+	return out, nil
+}
+
+func sqlserverCheckConstraints(ctx context.Context, db *sql.DB, objectID int64) ([]sqlserverConstraint, error) {
+	rows, err := db.QueryContext(ctx, `
+SELECT name, definition
+FROM sys.check_constraints
+WHERE parent_object_id = @p1
+ORDER BY name`, objectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []sqlserverConstraint
+	for rows.Next() {
+		var c sqlserverConstraint
+		if err := rows.Scan(&c.Name, &c.Def); err != nil {
+			return nil, err
+		}
+		c.Def = "CHECK " + c.Def
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// sqlserverIndexes reconstructs CREATE INDEX statements for indexes that do
+// not back a PRIMARY KEY or UNIQUE constraint. XML, spatial and hash indexes
+// need options the catalog query does not collect and are only noted.
+func sqlserverIndexes(ctx context.Context, db *sql.DB, objectID int64, qualified string) ([]string, []string, error) {
+	rows, err := db.QueryContext(ctx, `
+SELECT i.name, i.type_desc, i.is_unique, COALESCE(i.filter_definition, ''), c.name, ic.is_descending_key, ic.is_included_column
+FROM sys.indexes i
+LEFT JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+LEFT JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+WHERE i.object_id = @p1 AND i.type > 0 AND i.is_primary_key = 0 AND i.is_unique_constraint = 0
+ORDER BY i.name, ic.is_included_column, ic.key_ordinal, ic.index_column_id`, objectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	type indexDef struct {
+		name, typ, filter string
+		unique            bool
+		keys, include     []string
+	}
+	var indexes []*indexDef
+	for rows.Next() {
+		var name, typ, filter string
+		var unique bool
+		var col sql.NullString
+		var desc, included sql.NullBool
+		if err := rows.Scan(&name, &typ, &unique, &filter, &col, &desc, &included); err != nil {
+			return nil, nil, err
+		}
+		if len(indexes) == 0 || indexes[len(indexes)-1].name != name {
+			indexes = append(indexes, &indexDef{name: name, typ: typ, filter: filter, unique: unique})
+		}
+		ix := indexes[len(indexes)-1]
+		switch {
+		case !col.Valid:
+		case included.Bool:
+			ix.include = append(ix.include, quoteIdentSQLServer(col.String))
+		default:
+			ix.keys = append(ix.keys, sqlserverIndexColumn(col.String, desc.Bool))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var out, notes []string
+	for _, ix := range indexes {
+		switch ix.typ {
+		case "CLUSTERED", "NONCLUSTERED", "CLUSTERED COLUMNSTORE", "NONCLUSTERED COLUMNSTORE":
+		default:
+			notes = append(notes, fmt.Sprintf("index %s (%s) omitted", ix.name, ix.typ))
+			continue
+		}
+		var b strings.Builder
+		b.WriteString("CREATE ")
+		if ix.unique {
+			b.WriteString("UNIQUE ")
+		}
+		fmt.Fprintf(&b, "%s INDEX %s ON %s", ix.typ, quoteIdentSQLServer(ix.name), qualified)
+		switch {
+		case ix.typ == "CLUSTERED COLUMNSTORE":
+		case ix.typ == "NONCLUSTERED COLUMNSTORE":
+			// Columnstore columns are all reported as included.
+			fmt.Fprintf(&b, " (%s)", strings.Join(append(ix.keys, ix.include...), ", "))
+		default:
+			fmt.Fprintf(&b, " (%s)", strings.Join(ix.keys, ", "))
+			if len(ix.include) > 0 {
+				fmt.Fprintf(&b, " INCLUDE (%s)", strings.Join(ix.include, ", "))
+			}
+		}
+		if ix.filter != "" {
+			b.WriteString(" WHERE ")
+			b.WriteString(ix.filter)
+		}
+		b.WriteString(";")
+		out = append(out, b.String())
+	}
+	return out, notes, nil
+}
+
+func sqlserverIndexColumn(name string, desc bool) string {
+	if desc {
+		return quoteIdentSQLServer(name) + " DESC"
+	}
+	return quoteIdentSQLServer(name)
+}
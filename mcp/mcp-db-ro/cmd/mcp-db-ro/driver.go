@@ -3,17 +3,26 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"sync"
 	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 type DriverKind string
 
 const (
-	DriverPostgres DriverKind = "postgres"
-	DriverMySQL    DriverKind = "mysql"
+	DriverPostgres   DriverKind = "postgres"
+	DriverMySQL      DriverKind = "mysql"
+	DriverSQLite     DriverKind = "sqlite"
+	DriverSQLServer  DriverKind = "sqlserver"
+	DriverClickHouse DriverKind = "clickhouse"
+	DriverDuckDB     DriverKind = "duckdb"
+	DriverMongoDB    DriverKind = "mongodb"
 )
 
 type TableScope struct {
@@ -42,93 +51,140 @@ type DBDriver interface {
 	DescribeTable(ctx context.Context, db *sql.DB, ref TableRef) ([]map[string]any, error)
 	ListIndexes(ctx context.Context, db *sql.DB, ref TableRef) ([]map[string]any, error)
 	TablePartitions(ctx context.Context, db *sql.DB, ref TableRef) ([]map[string]any, error)
-	Explain(ctx context.Context, db *sql.DB, query string, format string) ([]map[string]any, error)
+	Explain(ctx context.Context, db queryer, query string, format string, args ...any) ([]map[string]any, error)
 	GetDDL(ctx context.Context, db *sql.DB, ref TableRef, includeIndexes bool) (DDLResult, error)
 }
 
+// dbOpener is implemented by drivers that open their pool themselves instead
+// of through sql.Open (e.g. to run setup statements on every new connection).
+type dbOpener interface {
+	OpenDB(cfg ConnectionConfig, dsn string) (*sql.DB, error)
+}
+
+// docStore is the counterpart of DBDriver for document databases. Collections
+// take the place of tables, and queries are find filters and aggregation
+// pipelines rather than SQL.
+type docStore interface {
+	Kind() DriverKind
+	Ping(ctx context.Context) error
+	Close(ctx context.Context) error
+	ListDatabases(ctx context.Context) ([]map[string]any, error)
+	ListCollections(ctx context.Context, database string) ([]map[string]any, error)
+	DescribeCollection(ctx context.Context, database, collection string, sampleSize int) ([]map[string]any, error)
+	ListIndexes(ctx context.Context, database, collection string) ([]map[string]any, error)
+	Find(ctx context.Context, database, collection string, q findQuery) (queryResult, error)
+	Aggregate(ctx context.Context, database, collection string, pipeline []bson.D, limit int) (queryResult, error)
+	Explain(ctx context.Context, database string, command bson.D, verbosity string) (map[string]any, error)
+}
+
+// queryResult is a page of db.query output. Rows is [][]any in column order,
+// or []map[string]any (without Columns) when objects were requested.
+type queryResult struct {
+	Columns             []resultColumn `json:"columns,omitempty"`
+	Rows                any            `json:"rows"`
+	ReadOnlyTransaction bool           `json:"readOnlyTransaction"`
+	// Truncated is set when more rows are available; NextCursor resumes them.
+	Truncated       bool   `json:"truncated"`
+	RowsRead        int    `json:"rowsRead"`
+	NextCursor      string `json:"nextCursor,omitempty"`
+	CursorExpiresAt string `json:"cursorExpiresAt,omitempty"`
+
+	columns []resultColumn // column order for text renderings of objects
+}
+
+func newQueryResult(page resultSet, objects bool) queryResult {
+	if objects {
+		return queryResult{Rows: page.maps(), columns: page.Columns}
+	}
+	return queryResult{Columns: page.Columns, Rows: page.Rows}
+}
+
+type explainResult struct {
+	Plan                []map[string]any `json:"plan"`
+	ReadOnlyTransaction bool             `json:"readOnlyTransaction"`
+}
+
 type dbClient struct {
-	cfg           ConnectionConfig
-	db            *sql.DB
-	driver        DBDriver
-	sqlDriverName string
+	cfg    ConnectionConfig
+	logger *log.Logger
+	spec   *driverSpec
+	db     *sql.DB
+	driver DBDriver
+
+	// flavor is the detected server flavor for kinds that have several
+	// (postgres, mysql); driver is then the flavor's variant. Both are set by
+	// the first successful check, under health.mu.
+	flavor Flavor
+
+	// tunnel, if set, carries the connection through SSH; tls holds the
+	// structured TLS settings.
+	tunnel *sshTunnel
+	tls    *tlsState
+
+	// pools enforces the limits on pools shared by all connections.
+	pools *poolLimiter
+
+	// docs is set instead of db/driver for document databases.
+	docs docStore
+
+	cursors *cursorStore
 
 	mu           sync.RWMutex
-	selectedDB   string
-	dbByDatabase map[string]*sql.DB // postgres only
-
-	// bootstrapPing indicates base db was validated on startup.
-	bootstrapPing bool
-}
-
-func normalizeDriver(d string) (DriverKind, string, error) {
-	switch strings.ToLower(strings.TrimSpace(d)) {
-	case "postgres", "postgresql", "pg":
-		return DriverPostgres, "pgx", nil
-	case "mysql":
-		return DriverMySQL, "mysql", nil
-	default:
-		return "", "", fmt.Errorf("unsupported driver: %s (supported: postgres, mysql)", d)
-	}
-}
-
-func newDriver(kind DriverKind) (DBDriver, error) {
-	switch kind {
-	case DriverPostgres:
-		return postgresDriver{}, nil
-	case DriverMySQL:
-		return mysqlDriver{}, nil
-	default:
-		return nil, fmt.Errorf("unsupported driver kind: %s", kind)
-	}
-}
-
-func (c *dbClient) normalizeScope(scope TableScope) (TableScope, error) {
-	switch c.driver.Kind() {
-	case DriverPostgres:
-		if strings.TrimSpace(scope.Schema) == "" {
-			scope.Schema = "public"
-		}
-		if strings.TrimSpace(scope.Database) == "" {
-			scope.Database = c.getSelectedDatabase()
-		}
-		if strings.TrimSpace(scope.Database) == "" {
-			scope.Database = strings.TrimSpace(c.cfg.Database)
-		}
-		return scope, nil
-	case DriverMySQL:
-		if strings.TrimSpace(scope.Database) == "" {
-			scope.Database = c.getSelectedDatabase()
-		}
-		if strings.TrimSpace(scope.Database) == "" {
-			scope.Database = c.cfg.DefaultDatabase
-		}
-		if strings.TrimSpace(scope.Database) == "" {
-			scope.Database = c.cfg.Database
-		}
-		return scope, nil
-	default:
-		return TableScope{}, fmt.Errorf("unsupported driver: %s", c.cfg.Driver)
-	}
-}
-
-func (c *dbClient) normalizeRef(ref TableRef) (TableRef, error) {
+	selectedDB   map[string]string  // by session ID, see db.useDatabase
+	dbByDatabase map[string]*dbPool // drivers with poolPerDatabase only
+
+	health connHealth
+}
+
+// normalizeScope fills in the database and schema left out of scope
+// following the driver's scopeRules and the database selected by the
+// session of ctx.
+func (c *dbClient) normalizeScope(ctx context.Context, scope TableScope) (TableScope, error) {
+	rules := c.spec.scope
+	if strings.TrimSpace(scope.Database) == "" {
+		scope.Database = c.getSelectedDatabase(ctx)
+	}
+	if strings.TrimSpace(scope.Database) == "" && rules.useDefaultDatabase {
+		scope.Database = strings.TrimSpace(c.cfg.DefaultDatabase)
+	}
+	if strings.TrimSpace(scope.Database) == "" {
+		scope.Database = strings.TrimSpace(c.cfg.Database)
+	}
+	if strings.TrimSpace(scope.Database) == "" {
+		scope.Database = rules.fallbackDatabase
+	}
+	switch {
+	case rules.noSchema:
+		scope.Schema = ""
+	case strings.TrimSpace(scope.Schema) == "":
+		scope.Schema = rules.defaultSchema
+	}
+	return scope, nil
+}
+
+func (c *dbClient) normalizeRef(ctx context.Context, ref TableRef) (TableRef, error) {
 	ref.Table = strings.TrimSpace(ref.Table)
 	if ref.Table == "" {
 		return TableRef{}, fmt.Errorf("table is required")
 	}
-	scope, err := c.normalizeScope(TableScope{Database: ref.Database, Schema: ref.Schema})
+	scope, err := c.normalizeScope(ctx, TableScope{Database: ref.Database, Schema: ref.Schema})
 	if err != nil {
 		return TableRef{}, err
 	}
+	if c.spec.scope.requireDatabase && strings.TrimSpace(scope.Database) == "" {
+		return TableRef{}, errDatabaseRequired
+	}
 	ref.Database = scope.Database
 	ref.Schema = scope.Schema
 	return ref, nil
 }
 
-func (c *dbClient) requireDatabase(database string) (string, error) {
+var errDatabaseRequired = errors.New("database is required for this operation (provide argument database or set database/defaultDatabase in config)")
+
+func (c *dbClient) requireDatabase(ctx context.Context, database string) (string, error) {
 	database = strings.TrimSpace(database)
 	if database == "" {
-		database = c.getSelectedDatabase()
+		database = c.getSelectedDatabase(ctx)
 	}
 	if database == "" {
 		database = strings.TrimSpace(c.cfg.DefaultDatabase)
@@ -137,21 +193,29 @@ func (c *dbClient) requireDatabase(database string) (string, error) {
 		database = strings.TrimSpace(c.cfg.Database)
 	}
 	if database == "" {
-		return "", fmt.Errorf("database is required for this operation (provide argument database or set database/defaultDatabase in config)")
+		return "", errDatabaseRequired
 	}
 	return database, nil
 }
 
-func (c *dbClient) setSelectedDatabase(database string) {
+// setSelectedDatabase selects database for the session of ctx only.
+func (c *dbClient) setSelectedDatabase(ctx context.Context, database string) {
 	c.mu.Lock()
-	c.selectedDB = strings.TrimSpace(database)
+	c.selectedDB[sessionFrom(ctx)] = strings.TrimSpace(database)
 	c.mu.Unlock()
 }
 
-func (c *dbClient) getSelectedDatabase() string {
+func (c *dbClient) getSelectedDatabase(ctx context.Context) string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.selectedDB
+	return c.selectedDB[sessionFrom(ctx)]
+}
+
+// endSession forgets the selection of a session that ended.
+func (c *dbClient) endSession(id string) {
+	c.mu.Lock()
+	delete(c.selectedDB, id)
+	c.mu.Unlock()
 }
 
 func (c *dbClient) dbForDatabase(ctx context.Context, database string) (*sql.DB, error) {
@@ -159,32 +223,28 @@ func (c *dbClient) dbForDatabase(ctx context.Context, database string) (*sql.DB,
 	if database == "" {
 		return c.db, nil
 	}
-	if c.driver.Kind() != DriverPostgres {
-		// MySQL doesn't require a separate pool per DB.
+	if !c.spec.poolPerDatabase {
 		return c.db, nil
 	}
 
 	c.mu.RLock()
-	if db, ok := c.dbByDatabase[database]; ok {
+	if p, ok := c.dbByDatabase[database]; ok {
 		c.mu.RUnlock()
-		return db, nil
+		p.touch()
+		return p.db, nil
 	}
 	c.mu.RUnlock()
 
-	cfgCopy := c.cfg
-	cfgCopy.Database = database
-	dsn, err := buildDSN(DriverPostgres, cfgCopy)
-	if err != nil {
+	if err := c.pools.acquire(c); err != nil {
 		return nil, err
 	}
-
-	db, err := sql.Open(c.sqlDriverName, dsn)
+	defer c.pools.release(c)
+	cfgCopy := c.cfg
+	cfgCopy.Database = database
+	db, err := openDB(c.spec, c.driver, cfgCopy, c.tunnel, c.tls)
 	if err != nil {
 		return nil, err
 	}
-	db.SetMaxOpenConns(4)
-	db.SetMaxIdleConns(4)
-	db.SetConnMaxLifetime(30 * time.Minute)
 
 	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	err = db.PingContext(pingCtx)
@@ -199,9 +259,12 @@ func (c *dbClient) dbForDatabase(ctx context.Context, database string) (*sql.DB,
 	if existing, ok := c.dbByDatabase[database]; ok {
 		c.mu.Unlock()
 		_ = db.Close()
-		return existing, nil
+		existing.touch()
+		return existing.db, nil
 	}
-	c.dbByDatabase[database] = db
+	p := &dbPool{db: db}
+	p.touch()
+	c.dbByDatabase[database] = p
 	c.mu.Unlock()
 	return db, nil
 }
@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"sort"
 	"strconv"
 	"strings"
@@ -9,68 +11,180 @@ import (
 	mysqlcfg "github.com/go-sql-driver/mysql"
 )
 
-func buildDSN(kind DriverKind, cfg ConnectionConfig) (string, error) {
-	host := strings.TrimSpace(cfg.Host)
+// serverLogin returns the host, port (or defaultPort) and username of a
+// server connection, which are required.
+func serverLogin(cfg ConnectionConfig, defaultPort int) (host string, port int, username string, err error) {
+	host = strings.TrimSpace(cfg.Host)
 	if host == "" {
-		return "", fmt.Errorf("host is required")
+		return "", 0, "", fmt.Errorf("host is required")
 	}
-	username := strings.TrimSpace(cfg.Username)
+	username = strings.TrimSpace(cfg.Username)
 	if username == "" {
-		return "", fmt.Errorf("username is required")
+		return "", 0, "", fmt.Errorf("username is required")
 	}
-	port := cfg.Port
+	port = cfg.Port
+	if port == 0 {
+		port = defaultPort
+	}
+	return host, port, username, nil
+}
 
-	switch kind {
-	case DriverPostgres:
-		if port == 0 {
-			port = 5432
-		}
-		parts := []string{
-			"host=" + pgConnValue(host),
-			"port=" + pgConnValue(strconv.Itoa(port)),
-			"user=" + pgConnValue(username),
-			"password=" + pgConnValue(cfg.Password),
-		}
-		if strings.TrimSpace(cfg.Database) != "" {
-			parts = append(parts, "dbname="+pgConnValue(cfg.Database))
-		}
-		if strings.TrimSpace(cfg.SSLMode) != "" {
-			parts = append(parts, "sslmode="+pgConnValue(cfg.SSLMode))
+func postgresDSN(cfg ConnectionConfig) (string, error) {
+	host, port, username, err := serverLogin(cfg, 5432)
+	if err != nil {
+		return "", err
+	}
+	parts := []string{
+		"host=" + pgConnValue(host),
+		"port=" + pgConnValue(strconv.Itoa(port)),
+		"user=" + pgConnValue(username),
+		"password=" + pgConnValue(cfg.Password),
+	}
+	if strings.TrimSpace(cfg.Database) != "" {
+		parts = append(parts, "dbname="+pgConnValue(cfg.Database))
+	}
+	if cfg.TLS.Mode != "" {
+		return "", fmt.Errorf("tls.mode is for mysql (use sslMode)")
+	}
+	sslMode := strings.TrimSpace(cfg.SSLMode)
+	if cfg.TLS.custom() {
+		// TLS settings imply TLS, verified against the CA bundle if given.
+		switch sslMode {
+		case "":
+			sslMode = "require"
+			if cfg.TLS.CAFile != "" {
+				sslMode = "verify-full"
+			}
+		case "disable":
+			return "", fmt.Errorf("tls settings conflict with sslMode disable")
+		case "allow", "prefer":
+			if cfg.TLS.Fingerprint != "" {
+				return "", fmt.Errorf("tls.fingerprint needs sslMode require, verify-ca or verify-full")
+			}
 		}
-		for _, kv := range sortedKV(cfg.Params) {
-			parts = append(parts, kv[0]+"="+pgConnValue(kv[1]))
+		// pgx reads the files itself; the other settings are applied by
+		// postgresOpenWith.
+		for k, v := range map[string]string{"sslrootcert": cfg.TLS.CAFile, "sslcert": cfg.TLS.CertFile, "sslkey": cfg.TLS.KeyFile} {
+			if _, ok := cfg.Params[k]; v != "" && ok {
+				return "", fmt.Errorf("set %s in tls or params, not both", k)
+			}
 		}
-		return strings.Join(parts, " "), nil
+		parts = appendNonEmpty(parts, "sslrootcert", expandHome(cfg.TLS.CAFile))
+		parts = appendNonEmpty(parts, "sslcert", expandHome(cfg.TLS.CertFile))
+		parts = appendNonEmpty(parts, "sslkey", expandHome(cfg.TLS.KeyFile))
+	}
+	if sslMode != "" {
+		parts = append(parts, "sslmode="+pgConnValue(sslMode))
+	}
+	for _, kv := range sortedKV(cfg.Params) {
+		parts = append(parts, kv[0]+"="+pgConnValue(kv[1]))
+	}
+	return strings.Join(parts, " "), nil
+}
 
-	case DriverMySQL:
-		if port == 0 {
-			port = 3306
+func mysqlDSN(cfg ConnectionConfig) (string, error) {
+	host, port, username, err := serverLogin(cfg, 3306)
+	if err != nil {
+		return "", err
+	}
+	c := mysqlcfg.NewConfig()
+	c.User = username
+	c.Passwd = cfg.Password
+	c.Net = "tcp"
+	c.Addr = fmt.Sprintf("%s:%d", host, port)
+	// DBName is optional in MySQL DSN; omitting it allows connecting to the server
+	// and inspecting multiple databases (subject to privileges).
+	c.DBName = strings.TrimSpace(cfg.Database)
+	c.ParseTime = true
+	switch {
+	case cfg.TLS.custom():
+		// Registered by mysqlOpenWith.
+		c.TLSConfig = mysqlTLSName(cfg.Name)
+		c.AllowFallbackToPlaintext = strings.EqualFold(cfg.TLS.Mode, "preferred")
+	case strings.TrimSpace(cfg.TLS.Mode) != "":
+		c.TLSConfig = strings.TrimSpace(cfg.TLS.Mode)
+	}
+	if len(cfg.Params) > 0 {
+		if c.Params == nil {
+			c.Params = map[string]string{}
 		}
-		c := mysqlcfg.NewConfig()
-		c.User = username
-		c.Passwd = cfg.Password
-		c.Net = "tcp"
-		c.Addr = fmt.Sprintf("%s:%d", host, port)
-		// DBName is optional in MySQL DSN; omitting it allows connecting to the server
-		// and inspecting multiple databases (subject to privileges).
-		c.DBName = strings.TrimSpace(cfg.Database)
-		c.ParseTime = true
-		if strings.TrimSpace(cfg.TLS) != "" {
-			c.TLSConfig = strings.TrimSpace(cfg.TLS)
+		for k, v := range cfg.Params {
+			c.Params[k] = v
 		}
-		if len(cfg.Params) > 0 {
-			if c.Params == nil {
-				c.Params = map[string]string{}
-			}
-			for k, v := range cfg.Params {
-				c.Params[k] = v
-			}
-		}
-		return c.FormatDSN(), nil
+	}
+	return c.FormatDSN(), nil
+}
+
+func sqlserverDSN(cfg ConnectionConfig) (string, error) {
+	host, port, username, err := serverLogin(cfg, 1433)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{}
+	for _, kv := range sortedKV(cfg.Params) {
+		q.Set(kv[0], kv[1])
+	}
+	if db := strings.TrimSpace(cfg.Database); db != "" {
+		q.Set("database", db)
+	}
+	u := url.URL{
+		Scheme:   "sqlserver",
+		User:     url.UserPassword(username, cfg.Password),
+		Host:     net.JoinHostPort(host, strconv.Itoa(port)),
+		RawQuery: q.Encode(),
+	}
+	return u.String(), nil
+}
+
+func clickhouseDSN(cfg ConnectionConfig) (string, error) {
+	host, port, username, err := serverLogin(cfg, 9000)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{}
+	for _, kv := range sortedKV(cfg.Params) {
+		q.Set(kv[0], kv[1])
+	}
+	// Unknown parameters are sent as settings with every query;
+	// readonly=1 makes the server refuse writes and setting changes, and
+	// introspection functions (addressToLine etc.) stay off.
+	q.Set("readonly", "1")
+	q.Set("allow_introspection_functions", "0")
+	u := url.URL{
+		Scheme:   "clickhouse",
+		User:     url.UserPassword(username, cfg.Password),
+		Host:     net.JoinHostPort(host, strconv.Itoa(port)),
+		Path:     "/" + strings.TrimSpace(cfg.Database),
+		RawQuery: q.Encode(),
+	}
+	return u.String(), nil
+}
+
+// sqliteDSN builds a modernc.org/sqlite URI that opens the file read-only.
+// query_only additionally rejects writes on the connection itself (e.g. to
+// the temp schema).
+func sqliteDSN(cfg ConnectionConfig) (string, error) {
+	path := strings.TrimSpace(cfg.Path)
+	if path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	q := url.Values{}
+	for _, kv := range sortedKV(cfg.Params) {
+		q.Add(kv[0], kv[1])
+	}
+	q.Set("mode", "ro")
+	if cfg.Immutable {
+		q.Set("immutable", "1")
+	}
+	q.Add("_pragma", "query_only(1)")
+	return "file:" + (&url.URL{Path: path}).EscapedPath() + "?" + q.Encode(), nil
+}
 
-	default:
-		return "", fmt.Errorf("unsupported driver kind: %s", kind)
+func appendNonEmpty(parts []string, key, value string) []string {
+	if value == "" {
+		return parts
 	}
+	return append(parts, key+"="+pgConnValue(value))
 }
 
 func pgConnValue(v string) string {
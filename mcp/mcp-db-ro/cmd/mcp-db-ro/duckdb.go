@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type duckdbDriver struct{}
+
+func init() {
+	registerDriver(driverSpec{
+		kind:      DriverDuckDB,
+		newDriver: func() DBDriver { return duckdbDriver{} },
+		dsn:       duckdbDSN,
+		// An empty database means wherever unqualified names resolve: the
+		// temp catalog (configured views), then the current database.
+		scope:         scopeRules{defaultSchema: "main"},
+		checkDatabase: checkDatabaseQuery(`SELECT database_name FROM duckdb_databases() WHERE database_name = ?`, "database not attached"),
+		dialect:       duckdbDialect,
+		placeholders:  placeholderDollar,
+	})
+}
+
+func (duckdbDriver) Kind() DriverKind { return DriverDuckDB }
+
+// ReadOnlyConnection implements readOnlyConnector: database files are opened
+// with access_mode=READ_ONLY (DuckDB has no read-only transactions), and an
+// in-memory database holds nothing but the configured views.
+func (duckdbDriver) ReadOnlyConnection() bool { return true }
+
+// duckdbInScope matches the catalog of a scope: the given database, or when
+// none is given the temp catalog (configured views) and the current database,
+// which is where unqualified names resolve. $1 is the database.
+const duckdbInScope = `(database_name = $1 OR ($1 = '' AND database_name IN ('temp', current_database())))`
+
+func (duckdbDriver) ListDatabases(ctx context.Context, db *sql.DB) ([]map[string]any, error) {
+	return queryAll(ctx, db, `
+SELECT database_name, path, type, readonly
+FROM duckdb_databases()
+WHERE NOT internal OR database_name = 'temp'
+ORDER BY database_name`)
+}
+
+func (duckdbDriver) ListSchemas(ctx context.Context, db *sql.DB) ([]map[string]any, error) {
+	return queryAll(ctx, db, `
+SELECT database_name, schema_name
+FROM duckdb_schemas()
+WHERE database_name <> 'system'
+ORDER BY database_name, schema_name`)
+}
+
+func (duckdbDriver) ListTables(ctx context.Context, db *sql.DB, scope TableScope) ([]map[string]any, error) {
+	return queryAll(ctx, db, `
+SELECT database_name, table_name, 'BASE TABLE' AS table_type, estimated_size, column_count
+FROM duckdb_tables()
+WHERE `+duckdbInScope+` AND schema_name = $2
+UNION ALL
+SELECT database_name, view_name, 'VIEW', NULL, column_count
+FROM duckdb_views()
+WHERE NOT internal AND `+duckdbInScope+` AND schema_name = $2
+ORDER BY table_name, database_name`, scope.Database, scope.Schema)
+}
+
+func (duckdbDriver) DescribeTable(ctx context.Context, db *sql.DB, ref TableRef) ([]map[string]any, error) {
+	database, err := duckdbResolveTable(ctx, db, ref)
+	if err != nil {
+		return nil, err
+	}
+	return queryAll(ctx, db, `
+SELECT
+  column_index AS ordinal_position,
+  column_name,
+  data_type,
+  CASE WHEN is_nullable THEN 'YES' ELSE 'NO' END AS is_nullable,
+  column_default,
+  comment
+FROM duckdb_columns()
+WHERE database_name = $1 AND schema_name = $2 AND table_name = $3
+ORDER BY column_index`, database, ref.Schema, ref.Table)
+}
+
+// ListIndexes lists ART indexes created with CREATE INDEX. Primary key and
+// unique constraints are part of the table definition (see getDDL).
+func (duckdbDriver) ListIndexes(ctx context.Context, db *sql.DB, ref TableRef) ([]map[string]any, error) {
+	database, err := duckdbResolveTable(ctx, db, ref)
+	if err != nil {
+		return nil, err
+	}
+	return queryAll(ctx, db, `
+SELECT index_name, is_unique, is_primary, expressions, sql
+FROM duckdb_indexes()
+WHERE database_name = $1 AND schema_name = $2 AND table_name = $3
+ORDER BY index_name`, database, ref.Schema, ref.Table)
+}
+
+// TablePartitions returns nothing: DuckDB tables are not partitioned (hive
+// partitioned files are read through views).
+func (duckdbDriver) TablePartitions(ctx context.Context, db *sql.DB, ref TableRef) ([]map[string]any, error) {
+	return []map[string]any{}, nil
+}
+
+func (duckdbDriver) Explain(ctx context.Context, db queryer, query, format string, args ...any) ([]map[string]any, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "text":
+		return queryAll(ctx, db, "EXPLAIN "+query, args...)
+	case "json":
+		return queryAll(ctx, db, "EXPLAIN (FORMAT json) "+query, args...)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s (duckdb supports: text, json)", format)
+	}
+}
+
+// duckdbResolveTable returns the catalog ref.Table belongs to. Without a
+// database it follows DuckDB's name resolution: temp first, then the current
+// database.
+func duckdbResolveTable(ctx context.Context, db *sql.DB, ref TableRef) (string, error) {
+	var database string
+	err := db.QueryRowContext(ctx, `
+SELECT database_name
+FROM (
+  SELECT database_name, schema_name, table_name FROM duckdb_tables()
+  UNION ALL
+  SELECT database_name, schema_name, view_name FROM duckdb_views() WHERE NOT internal
+)
+WHERE `+duckdbInScope+` AND schema_name = $2 AND table_name = $3
+ORDER BY database_name = 'temp' DESC
+LIMIT 1`, ref.Database, ref.Schema, ref.Table).Scan(&database)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("table not found: %s.%s", ref.Schema, ref.Table)
+	}
+	return database, err
+}
+
+// duckdbDSN returns the database file (or "" for an in-memory database) with
+// access_mode=READ_ONLY. Params are passed as DuckDB configuration options.
+func duckdbDSN(cfg ConnectionConfig) (string, error) {
+	path := strings.TrimSpace(cfg.Path)
+	q := url.Values{}
+	for _, kv := range sortedKV(cfg.Params) {
+		q.Set(kv[0], kv[1])
+	}
+	if path == "" || path == ":memory:" {
+		// In-memory databases cannot be opened read-only.
+		if q.Get("access_mode") != "" {
+			return "", fmt.Errorf("params.access_mode cannot be set for an in-memory database")
+		}
+		if len(q) == 0 {
+			return "", nil
+		}
+		return "?" + q.Encode(), nil
+	}
+	if strings.ContainsAny(path, "?#") {
+		return "", fmt.Errorf("path must not contain '?' or '#'")
+	}
+	q.Set("access_mode", "READ_ONLY")
+	return path + "?" + q.Encode(), nil
+}
+
+// duckdbInitStatements returns the statements run on every new connection:
+// on the first one, file access is confined to AllowedDirectories and the
+// configuration is locked so queries cannot lift the restriction; then the
+// configured views are created (temporary views are per connection).
+func duckdbInitStatements(cfg ConnectionConfig) (lockdown, views []string, err error) {
+	dirs := make([]string, 0, len(cfg.AllowedDirectories))
+	for _, d := range cfg.AllowedDirectories {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		abs, err := filepath.Abs(d)
+		if err != nil {
+			return nil, nil, fmt.Errorf("allowedDirectories: %w", err)
+		}
+		dirs = append(dirs, abs)
+	}
+
+	names := make([]string, 0, len(cfg.Views))
+	for name := range cfg.Views {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" {
+			return nil, nil, fmt.Errorf("views: empty view name")
+		}
+		glob := strings.TrimSpace(cfg.Views[name])
+		if strings.Contains(glob, "://") {
+			return nil, nil, fmt.Errorf("view %s: only local files are supported", name)
+		}
+		abs, err := filepath.Abs(glob)
+		if err != nil {
+			return nil, nil, fmt.Errorf("view %s: %w", name, err)
+		}
+		if !withinDirs(abs, dirs) {
+			return nil, nil, fmt.Errorf("view %s: %s is outside allowedDirectories", name, glob)
+		}
+		// DuckDB picks the reader (Parquet, CSV, JSON) from the extension.
+		views = append(views, fmt.Sprintf("CREATE OR REPLACE TEMP VIEW %s AS SELECT * FROM %s",
+			quoteIdentPG(name), quoteDuckDBString(abs)))
+	}
+
+	quoted := make([]string, len(dirs))
+	for i, d := range dirs {
+		quoted[i] = quoteDuckDBString(d + string(filepath.Separator))
+	}
+	lockdown = []string{
+		"SET allowed_directories = [" + strings.Join(quoted, ", ") + "]",
+		"SET enable_external_access = false",
+		"SET autoinstall_known_extensions = false",
+		"SET autoload_known_extensions = false",
+		"SET lock_configuration = true",
+	}
+	return lockdown, views, nil
+}
+
+// withinDirs reports whether path lies inside one of dirs (all absolute).
+func withinDirs(path string, dirs []string) bool {
+	for _, d := range dirs {
+		rel, err := filepath.Rel(d, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func quoteDuckDBString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
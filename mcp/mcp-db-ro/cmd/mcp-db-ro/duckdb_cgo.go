@@ -0,0 +1,47 @@
+//go:build cgo
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+
+	duckdb "github.com/duckdb/duckdb-go/v2"
+)
+
+// OpenDB implements dbOpener. DuckDB settings are per database instance and
+// temporary views per connection, so both are applied from the connector's
+// init hook rather than through the DSN.
+func (duckdbDriver) OpenDB(cfg ConnectionConfig, dsn string) (*sql.DB, error) {
+	lockdown, views, err := duckdbInitStatements(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var mu sync.Mutex
+	locked := false
+	connector, err := duckdb.NewConnector(dsn, func(conn driver.ExecerContext) error {
+		ctx := context.Background()
+		mu.Lock()
+		defer mu.Unlock()
+		if !locked {
+			for _, stmt := range lockdown {
+				if _, err := conn.ExecContext(ctx, stmt, nil); err != nil {
+					return err
+				}
+			}
+			locked = true
+		}
+		for _, stmt := range views {
+			if _, err := conn.ExecContext(ctx, stmt, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(connector), nil
+}
@@ -0,0 +1,14 @@
+//go:build !cgo
+
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// OpenDB implements dbOpener. The DuckDB driver links the C++ library, which
+// needs cgo; the default release build (CGO_ENABLED=0) leaves it out.
+func (duckdbDriver) OpenDB(cfg ConnectionConfig, dsn string) (*sql.DB, error) {
+	return nil, fmt.Errorf("duckdb requires a binary built with CGO_ENABLED=1 (make build-duckdb)")
+}
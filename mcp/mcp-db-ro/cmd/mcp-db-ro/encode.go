@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+)
+
+// valueEncoder is implemented by drivers that convert scanned values into a
+// faithful JSON representation based on the column's database type.
+type valueEncoder interface {
+	// ColumnEncoder returns the conversion for values of col, or nil to use
+	// normalizeSQLValue.
+	ColumnEncoder(col resultColumn) func(any) any
+}
+
+// encoder returns the driver's value encoder, or nil.
+func (c *dbClient) encoder() valueEncoder {
+	enc, _ := c.driver.(valueEncoder)
+	return enc
+}
+
+// columnEncoders resolves the per-column conversions for a result.
+func columnEncoders(enc valueEncoder, cols []resultColumn) []func(any) any {
+	out := make([]func(any) any, len(cols))
+	for i, c := range cols {
+		if enc != nil {
+			out[i] = enc.ColumnEncoder(c)
+		}
+		if out[i] == nil {
+			out[i] = normalizeSQLValue
+		}
+	}
+	return out
+}
+
+// binaryValue is how binary column values are returned: base64 data tagged
+// with the column's type, so they cannot be mistaken for text.
+type binaryValue struct {
+	Type   string `json:"type"`
+	Base64 string `json:"base64"`
+}
+
+func binaryEncoder(typ string) func(any) any {
+	typ = strings.ToLower(typ)
+	return func(v any) any {
+		b, ok := v.([]byte)
+		if !ok {
+			return normalizeSQLValue(v)
+		}
+		return binaryValue{Type: typ, Base64: base64.StdEncoding.EncodeToString(b)}
+	}
+}
+
+// encodeDecimal keeps exact numeric values as strings; floats would round.
+func encodeDecimal(v any) any {
+	switch x := v.(type) {
+	case []byte:
+		return string(x)
+	default:
+		return normalizeSQLValue(v)
+	}
+}
+
+// encodeFloat returns NaN and infinities, which JSON numbers cannot hold, as
+// the strings Postgres uses for them.
+func encodeFloat(v any) any {
+	f, ok := v.(float64)
+	switch {
+	case !ok:
+		return normalizeSQLValue(v)
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return f
+}
+
+// encodeJSON returns json/jsonb documents as parsed JSON. Invalid documents
+// fall back to text.
+func encodeJSON(v any) any {
+	var b []byte
+	switch x := v.(type) {
+	case []byte:
+		b = x
+	case string:
+		b = []byte(x)
+	default:
+		return normalizeSQLValue(v)
+	}
+	if !json.Valid(b) {
+		return string(b)
+	}
+	return json.RawMessage(append([]byte(nil), b...))
+}
+
+// encodeTimestamp renders an instant as RFC 3339 with an explicit zone.
+// Values without a zone in the database are interpreted as UTC.
+func encodeTimestamp(v any) any {
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	return normalizeSQLValue(v)
+}
+
+// encodeDate renders a calendar date as an RFC 3339 full-date.
+func encodeDate(v any) any {
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.DateOnly)
+	}
+	return normalizeSQLValue(v)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+)
+
+// ColumnEncoder implements valueEncoder. clickhouse-go returns native Go
+// values: unsigned and 128/256-bit integers, float32, decimals as strings,
+// arrays as slices and maps as maps.
+func (clickhouseDriver) ColumnEncoder(col resultColumn) func(any) any {
+	typ := clickhouseBaseType(col.DatabaseType)
+	switch {
+	case typ == "Float32" || typ == "Float64":
+		return encodeClickHouseFloat
+	case typ == "Date" || typ == "Date32":
+		return encodeDate
+	case strings.HasPrefix(typ, "DateTime"):
+		return encodeTimestamp
+	case strings.HasPrefix(typ, "Decimal"):
+		return encodeDecimal
+	case strings.HasSuffix(typ, "Int128") || strings.HasSuffix(typ, "Int256"):
+		return encodeClickHouseBigInt
+	case typ == "String" || strings.HasPrefix(typ, "FixedString("):
+		// ClickHouse strings are arbitrary bytes.
+		return encodeClickHouseString
+	case strings.HasPrefix(typ, "Array("), strings.HasPrefix(typ, "Map("), strings.HasPrefix(typ, "Tuple("):
+		return encodeClickHouseNested
+	}
+	return nil
+}
+
+// clickhouseBaseType strips the Nullable and LowCardinality wrappers.
+func clickhouseBaseType(typ string) string {
+	for {
+		switch {
+		case strings.HasPrefix(typ, "Nullable(") && strings.HasSuffix(typ, ")"):
+			typ = typ[len("Nullable(") : len(typ)-1]
+		case strings.HasPrefix(typ, "LowCardinality(") && strings.HasSuffix(typ, ")"):
+			typ = typ[len("LowCardinality(") : len(typ)-1]
+		default:
+			return typ
+		}
+	}
+}
+
+func encodeClickHouseFloat(v any) any {
+	if f, ok := v.(float32); ok {
+		if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+			return encodeFloat(float64(f))
+		}
+		return f
+	}
+	return encodeFloat(v)
+}
+
+// encodeClickHouseBigInt keeps 128/256-bit integers exact as strings.
+func encodeClickHouseBigInt(v any) any {
+	switch x := v.(type) {
+	case *big.Int:
+		return x.String()
+	case big.Int:
+		return x.String()
+	}
+	return normalizeSQLValue(v)
+}
+
+func encodeClickHouseString(v any) any {
+	if s, ok := v.(string); ok && !utf8.ValidString(s) {
+		return binaryEncoder("string")([]byte(s))
+	}
+	return normalizeSQLValue(v)
+}
+
+// encodeClickHouseNested replaces the values JSON cannot hold (NaN and
+// infinite floats, big integers) inside arrays, maps and tuples.
+func encodeClickHouseNested(v any) any {
+	switch x := v.(type) {
+	case []float64:
+		out := make([]any, len(x))
+		for i, f := range x {
+			out[i] = encodeFloat(f)
+		}
+		return out
+	case []float32:
+		out := make([]any, len(x))
+		for i, f := range x {
+			out[i] = encodeClickHouseFloat(f)
+		}
+		return out
+	case []any:
+		out := make([]any, len(x))
+		for i, e := range x {
+			out[i] = encodeClickHouseNested(e)
+		}
+		return out
+	case float64, float32:
+		return encodeClickHouseFloat(x)
+	case *big.Int:
+		return x.String()
+	}
+	return normalizeSQLValue(v)
+}
@@ -0,0 +1,90 @@
+//go:build cgo
+
+package main
+
+import (
+	"math/big"
+	"time"
+
+	duckdb "github.com/duckdb/duckdb-go/v2"
+)
+
+// ColumnEncoder implements valueEncoder. duckdb-go returns BLOB and UUID
+// values as raw byte strings, DECIMAL as duckdb.Decimal, HUGEINT as *big.Int
+// and nested types as slices and maps.
+func (duckdbDriver) ColumnEncoder(col resultColumn) func(any) any {
+	switch col.DatabaseType {
+	case "BLOB":
+		return encodeDuckDBBlob
+	case "UUID":
+		return encodeDuckDBUUID
+	case "DATE":
+		return encodeDate
+	case "TIME":
+		return encodeDuckDBTime
+	}
+	return encodeDuckDBValue
+}
+
+func encodeDuckDBBlob(v any) any {
+	if s, ok := v.(string); ok {
+		return binaryEncoder("blob")([]byte(s))
+	}
+	return binaryEncoder("blob")(v)
+}
+
+func encodeDuckDBUUID(v any) any {
+	var u duckdb.UUID
+	switch x := v.(type) {
+	case string:
+		if len(x) != len(u) {
+			return x
+		}
+		copy(u[:], x)
+	case []byte:
+		if len(x) != len(u) {
+			return normalizeSQLValue(v)
+		}
+		copy(u[:], x)
+	default:
+		return normalizeSQLValue(v)
+	}
+	return u.String()
+}
+
+func encodeDuckDBTime(v any) any {
+	if t, ok := v.(time.Time); ok {
+		return t.Format("15:04:05.999999")
+	}
+	return normalizeSQLValue(v)
+}
+
+// encodeDuckDBValue keeps decimals and big integers exact as strings and
+// replaces the values JSON cannot hold, also inside lists and structs.
+func encodeDuckDBValue(v any) any {
+	switch x := v.(type) {
+	case duckdb.Decimal:
+		return x.String()
+	case *big.Int:
+		return x.String()
+	case float64:
+		return encodeFloat(x)
+	case float32:
+		return encodeClickHouseFloat(x)
+	case time.Time:
+		return encodeTimestamp(x)
+	case []any:
+		out := make([]any, len(x))
+		for i, e := range x {
+			out[i] = encodeDuckDBValue(e)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(x))
+		for k, e := range x {
+			out[k] = encodeDuckDBValue(e)
+		}
+		return out
+	}
+	return normalizeSQLValue(v)
+}
@@ -0,0 +1,55 @@
+package main
+
+import "encoding/binary"
+
+// ColumnEncoder implements valueEncoder. go-sql-driver/mysql returns integers
+// and floats as numbers, DATETIME/TIMESTAMP/DATE as time.Time (parseTime is
+// set) and everything else as raw bytes.
+func (mysqlDriver) ColumnEncoder(col resultColumn) func(any) any {
+	switch typ := col.DatabaseType; typ {
+	case "BINARY", "VARBINARY", "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB":
+		return binaryEncoder(typ)
+	case "DECIMAL":
+		return encodeDecimal
+	case "FLOAT", "DOUBLE":
+		return encodeFloat
+	case "JSON":
+		return encodeJSON
+	case "DATETIME", "TIMESTAMP":
+		return encodeTimestamp
+	case "DATE":
+		return encodeDate
+	case "BIT":
+		return encodeMySQLBit
+	case "GEOMETRY":
+		return encodeMySQLGeometry
+	}
+	return nil
+}
+
+// encodeMySQLBit returns BIT(n) values as unsigned integers.
+func encodeMySQLBit(v any) any {
+	b, ok := v.([]byte)
+	if !ok || len(b) > 8 {
+		return normalizeSQLValue(v)
+	}
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n
+}
+
+// encodeMySQLGeometry converts MySQL's internal geometry format (a 4-byte
+// little-endian SRID followed by WKB) into GeoJSON.
+func encodeMySQLGeometry(v any) any {
+	b, ok := v.([]byte)
+	if !ok || len(b) < 9 {
+		return normalizeSQLValue(v)
+	}
+	g, err := wkbToGeoJSON(b[4:], binary.LittleEndian.Uint32(b))
+	if err != nil {
+		return binaryEncoder("geometry")(v)
+	}
+	return g
+}
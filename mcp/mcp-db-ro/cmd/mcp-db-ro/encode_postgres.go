@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ColumnEncoder implements valueEncoder. pgx already returns numeric as exact
+// text and timestamps as time.Time; arrays and types it does not know (such
+// as PostGIS geometry) arrive in Postgres text format.
+func (postgresDriver) ColumnEncoder(col resultColumn) func(any) any {
+	typ := col.DatabaseType
+	switch {
+	case typ == "BYTEA":
+		return binaryEncoder(typ)
+	case typ == "NUMERIC":
+		return encodeDecimal
+	case typ == "FLOAT4" || typ == "FLOAT8":
+		return encodeFloat
+	case typ == "JSON" || typ == "JSONB":
+		return encodeJSON
+	case typ == "TIMESTAMP" || typ == "TIMESTAMPTZ":
+		return encodeTimestamp
+	case typ == "DATE":
+		return encodeDate
+	case strings.HasPrefix(typ, "_"):
+		elem := pgTextEncoder(strings.TrimPrefix(typ, "_"))
+		return func(v any) any {
+			s, ok := v.(string)
+			if !ok {
+				return normalizeSQLValue(v)
+			}
+			arr, err := parsePGArray(s, elem)
+			if err != nil {
+				return s
+			}
+			return arr
+		}
+	case isDigits(typ):
+		// Types unknown to pgx are reported by OID. PostGIS geometry and
+		// geography come back as hex EWKB; anything else stays text.
+		return func(v any) any {
+			s, ok := v.(string)
+			if !ok {
+				return normalizeSQLValue(v)
+			}
+			b, err := hex.DecodeString(s)
+			if err != nil || len(b) < 5 {
+				return s
+			}
+			g, err := wkbToGeoJSON(b, 0)
+			if err != nil {
+				return s
+			}
+			return g
+		}
+	}
+	return nil
+}
+
+// pgTextEncoder converts one element of an array of elemType from Postgres
+// text format.
+func pgTextEncoder(elemType string) func(string) any {
+	switch elemType {
+	case "INT2", "INT4", "INT8", "OID":
+		return func(s string) any {
+			if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return n
+			}
+			return s
+		}
+	case "FLOAT4", "FLOAT8":
+		return func(s string) any {
+			if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+				return f
+			}
+			return s
+		}
+	case "BOOL":
+		return func(s string) any { return s == "t" }
+	case "JSON", "JSONB":
+		return func(s string) any { return encodeJSON(s) }
+	case "BYTEA":
+		return func(s string) any {
+			b, err := hex.DecodeString(strings.TrimPrefix(s, `\x`))
+			if err != nil {
+				return s
+			}
+			return binaryValue{Type: "bytea", Base64: base64.StdEncoding.EncodeToString(b)}
+		}
+	case "TIMESTAMPTZ":
+		return func(s string) any { return pgTextTimestamp(s, "2006-01-02 15:04:05.999999999Z07") }
+	case "TIMESTAMP":
+		return func(s string) any { return pgTextTimestamp(s, "2006-01-02 15:04:05.999999999") }
+	}
+	return func(s string) any { return s }
+}
+
+func pgTextTimestamp(s, layout string) any {
+	for _, l := range []string{layout, layout + ":00"} {
+		if t, err := time.Parse(l, s); err == nil {
+			return t.Format(time.RFC3339Nano)
+		}
+	}
+	return s
+}
+
+// parsePGArray parses a Postgres array literal such as {1,NULL,"a b"} or
+// [0:1]={{1,2},{3,4}} into nested slices. elem converts unquoted and quoted
+// element text; NULL becomes nil.
+func parsePGArray(s string, elem func(string) any) (any, error) {
+	// Arrays with non-default lower bounds carry a dimension prefix.
+	if strings.HasPrefix(s, "[") {
+		i := strings.Index(s, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid array dimensions")
+		}
+		s = s[i+1:]
+	}
+	p := &pgArrayParser{s: s, elem: elem}
+	v, err := p.array()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(s) {
+		return nil, fmt.Errorf("trailing data after array")
+	}
+	return v, nil
+}
+
+type pgArrayParser struct {
+	s    string
+	pos  int
+	elem func(string) any
+}
+
+func (p *pgArrayParser) array() ([]any, error) {
+	if p.pos >= len(p.s) || p.s[p.pos] != '{' {
+		return nil, fmt.Errorf("expected '{' at %d", p.pos)
+	}
+	p.pos++
+	out := []any{}
+	if p.pos < len(p.s) && p.s[p.pos] == '}' {
+		p.pos++
+		return out, nil
+	}
+	for {
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("unterminated array")
+		}
+		switch p.s[p.pos] {
+		case '{':
+			sub, err := p.array()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, sub)
+		case '"':
+			text, err := p.quoted()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, p.elem(text))
+		default:
+			start := p.pos
+			for p.pos < len(p.s) && p.s[p.pos] != ',' && p.s[p.pos] != '}' {
+				p.pos++
+			}
+			text := strings.TrimSpace(p.s[start:p.pos])
+			if strings.EqualFold(text, "NULL") {
+				out = append(out, nil)
+			} else {
+				out = append(out, p.elem(text))
+			}
+		}
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("unterminated array")
+		}
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return out, nil
+		default:
+			return nil, fmt.Errorf("unexpected %q at %d", p.s[p.pos], p.pos)
+		}
+	}
+}
+
+func (p *pgArrayParser) quoted() (string, error) {
+	p.pos++ // opening quote
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		ch := p.s[p.pos]
+		switch ch {
+		case '\\':
+			if p.pos+1 >= len(p.s) {
+				return "", fmt.Errorf("unterminated escape")
+			}
+			b.WriteByte(p.s[p.pos+1])
+			p.pos += 2
+		case '"':
+			p.pos++
+			return b.String(), nil
+		default:
+			b.WriteByte(ch)
+			p.pos++
+		}
+	}
+	return "", fmt.Errorf("unterminated quoted element")
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isSQLDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"time"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// ColumnEncoder implements valueEncoder. go-mssqldb returns decimal and money
+// values as text bytes, temporal types as time.Time (zone-less types in UTC)
+// and uniqueidentifier as its 16 wire-order bytes.
+func (sqlserverDriver) ColumnEncoder(col resultColumn) func(any) any {
+	switch typ := col.DatabaseType; typ {
+	case "BINARY", "VARBINARY", "IMAGE":
+		return binaryEncoder(typ)
+	case "DECIMAL", "MONEY", "SMALLMONEY":
+		return encodeDecimal
+	case "FLOAT", "REAL":
+		return encodeFloat
+	case "DATETIME", "DATETIME2", "SMALLDATETIME", "DATETIMEOFFSET":
+		return encodeTimestamp
+	case "DATE":
+		return encodeDate
+	case "TIME":
+		return encodeSQLServerTime
+	case "UNIQUEIDENTIFIER":
+		return encodeSQLServerGUID
+	}
+	return nil
+}
+
+// encodeSQLServerTime renders a time of day (returned on 0001-01-01).
+func encodeSQLServerTime(v any) any {
+	if t, ok := v.(time.Time); ok {
+		return t.Format("15:04:05.9999999")
+	}
+	return normalizeSQLValue(v)
+}
+
+func encodeSQLServerGUID(v any) any {
+	var u mssql.UniqueIdentifier
+	if b, ok := v.([]byte); !ok || u.Scan(b) != nil {
+		return normalizeSQLValue(v)
+	}
+	return u.String()
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	exportCSV     = "csv"
+	exportNDJSON  = "ndjson"
+	exportParquet = "parquet"
+)
+
+type exportResult struct {
+	Path                string `json:"path"`
+	Format              string `json:"format"`
+	Rows                int64  `json:"rows"`
+	Bytes               int64  `json:"bytes"`
+	SHA256              string `json:"sha256"`
+	ReadOnlyTransaction bool   `json:"readOnlyTransaction"`
+}
+
+// exportWriter encodes a streamed result into one file format.
+type exportWriter interface {
+	start(cols []resultColumn) error
+	write(row []any) error
+	// close flushes buffered data; it does not close the underlying file.
+	close() error
+}
+
+// exportQuery streams the full result of a read-only query into a file under
+// the export directory. The file is removed if the export fails or exceeds the
+// configured row/byte budget. With overwrite the result is written to a
+// temporary file beside the target and renamed over it only on success, so a
+// failed export leaves an existing file untouched.
+func (s *dbService) exportQuery(ctx context.Context, conn, database, query string, params []queryParam, format, path string, overwrite bool) (any, error) {
+	export := s.exportConfig()
+	if strings.TrimSpace(export.Dir) == "" {
+		return nil, fmt.Errorf("export is not configured (set export.dir)")
+	}
+	c, err := s.getSQLClient(ctx, conn, "db.exportQuery")
+	if err != nil {
+		return nil, err
+	}
+	if format = strings.ToLower(strings.TrimSpace(format)); format == "" {
+		format = exportCSV
+	}
+	switch format {
+	case exportCSV, exportNDJSON, exportParquet:
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s (use csv, ndjson or parquet)", format)
+	}
+	name, err := exportName(path, format)
+	if err != nil {
+		return nil, err
+	}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if err := checkReadOnlySQL(c.driver.Kind(), query); err != nil {
+		return nil, err
+	}
+	query, args, err := c.bindParams(query, params)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := c.normalizeScope(ctx, TableScope{Database: database})
+	if err != nil {
+		return nil, err
+	}
+	db, err := c.dbForDatabase(ctx, scope.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	// os.Root refuses names that escape the directory, including via symlinks.
+	root, err := os.OpenRoot(export.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("export dir: %w", err)
+	}
+	defer root.Close()
+	if err := mkdirAllIn(root, filepath.Dir(name)); err != nil {
+		return nil, err
+	}
+	tmp := name
+	if overwrite {
+		tmp = filepath.Join(filepath.Dir(name), "."+filepath.Base(name)+"."+randomHex(4)+".tmp")
+	}
+	f, err := root.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("export file %s already exists (set overwrite to replace it)", name)
+		}
+		return nil, err
+	}
+
+	out := &budgetWriter{w: f, h: sha256.New(), max: export.maxBytes()}
+	var w exportWriter
+	switch format {
+	case exportCSV:
+		w = newCSVExport(out)
+	case exportNDJSON:
+		w = newNDJSONExport(out)
+	case exportParquet:
+		w = newParquetExport(out)
+	}
+
+	maxRows := export.maxRows()
+	var rows int64
+	readOnly, err := c.readOnlyWithin(ctx, db, export.timeout(), func(ctx context.Context, q queryer) error {
+		return streamQuery(ctx, q, c.encoder(), query, args, w.start, func(row []any) error {
+			if rows++; rows > maxRows {
+				return fmt.Errorf("export exceeds maxRows (%d)", maxRows)
+			}
+			return w.write(row)
+		})
+	})
+	if err == nil {
+		err = w.close()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && tmp != name {
+		err = renameIn(root, export.Dir, tmp, name)
+	}
+	if err != nil {
+		if rerr := root.Remove(tmp); rerr != nil && !errors.Is(rerr, fs.ErrNotExist) {
+			c.logf("connection %s: remove failed export %s: %v", c.cfg.Name, tmp, rerr)
+		}
+		return nil, err
+	}
+
+	return exportResult{
+		Path:                filepath.Join(export.Dir, name),
+		Format:              format,
+		Rows:                rows,
+		Bytes:               out.n,
+		SHA256:              hex.EncodeToString(out.h.Sum(nil)),
+		ReadOnlyTransaction: readOnly,
+	}, nil
+}
+
+// renameIn renames tmp over name; both are names in the same directory under
+// root, which was opened from dir. os.Root gains Rename only in Go 1.25, so the
+// rename goes through the path, and is refused if the path no longer leads to
+// the directory found through root. That narrows, but does not close, the
+// window in which a directory swapped for a symlink redirects the rename. A
+// symlink at name itself is replaced, not followed.
+func renameIn(root *os.Root, dir, tmp, name string) error {
+	parent, err := root.OpenRoot(filepath.Dir(name))
+	if err != nil {
+		return err
+	}
+	defer parent.Close()
+	want, err := parent.Stat(".")
+	if err != nil {
+		return err
+	}
+	parentPath := filepath.Join(dir, filepath.Dir(name))
+	got, err := os.Stat(parentPath)
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(want, got) {
+		return fmt.Errorf("export directory %s changed during the export", filepath.Dir(name))
+	}
+	return os.Rename(filepath.Join(parentPath, filepath.Base(tmp)), filepath.Join(parentPath, filepath.Base(name)))
+}
+
+// exportName validates a path relative to the export root, or picks a
+// timestamped name if none is given.
+func exportName(path, format string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return fmt.Sprintf("export-%s-%s.%s", time.Now().UTC().Format("20060102T150405Z"), randomHex(4), format), nil
+	}
+	if filepath.IsAbs(path) || !filepath.IsLocal(path) {
+		return "", fmt.Errorf("export path must be relative to the export directory: %s", path)
+	}
+	return filepath.Clean(path), nil
+}
+
+func mkdirAllIn(root *os.Root, dir string) error {
+	if dir == "." {
+		return nil
+	}
+	cur := ""
+	for _, part := range strings.Split(dir, string(filepath.Separator)) {
+		cur = filepath.Join(cur, part)
+		if err := root.Mkdir(cur, 0o755); err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// budgetWriter counts and hashes what is written and fails once more than max
+// bytes would be written.
+type budgetWriter struct {
+	w   io.Writer
+	h   hash.Hash
+	n   int64
+	max int64
+}
+
+func (b *budgetWriter) Write(p []byte) (int, error) {
+	if b.n+int64(len(p)) > b.max {
+		return 0, fmt.Errorf("export exceeds maxBytes (%d)", b.max)
+	}
+	n, err := b.w.Write(p)
+	b.h.Write(p[:n])
+	b.n += int64(n)
+	return n, err
+}
+
+type csvExport struct {
+	buf    *bufio.Writer
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVExport(w io.Writer) *csvExport {
+	buf := bufio.NewWriter(w)
+	return &csvExport{buf: buf, w: csv.NewWriter(buf)}
+}
+
+func (e *csvExport) start(cols []resultColumn) error {
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = c.Name
+	}
+	e.record = make([]string, len(cols))
+	return e.w.Write(header)
+}
+
+func (e *csvExport) write(row []any) error {
+	for i, v := range row {
+		s, err := cellText(v, "")
+		if err != nil {
+			return err
+		}
+		e.record[i] = s
+	}
+	if err := e.w.Write(e.record); err != nil {
+		return err
+	}
+	// Surface budget errors from the underlying writer early.
+	return e.w.Error()
+}
+
+func (e *csvExport) close() error {
+	e.w.Flush()
+	if err := e.w.Error(); err != nil {
+		return err
+	}
+	return e.buf.Flush()
+}
+
+type ndjsonExport struct {
+	buf     *bufio.Writer
+	columns []string
+	line    bytes.Buffer
+}
+
+func newNDJSONExport(w io.Writer) *ndjsonExport {
+	return &ndjsonExport{buf: bufio.NewWriter(w)}
+}
+
+func (e *ndjsonExport) start(cols []resultColumn) error {
+	e.columns = make([]string, len(cols))
+	for i, c := range cols {
+		e.columns[i] = c.Name
+	}
+	return nil
+}
+
+func (e *ndjsonExport) write(row []any) error {
+	e.line.Reset()
+	if err := appendNDJSON(&e.line, e.columns, row); err != nil {
+		return err
+	}
+	_, err := e.buf.Write(e.line.Bytes())
+	return err
+}
+
+func (e *ndjsonExport) close() error { return e.buf.Flush() }
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetKind is the physical type a result column is stored as. Integers,
+// floats and booleans keep their type; everything else (decimals, temporal
+// values, JSON, ...) is stored as the same text db.query returns.
+type parquetKind int
+
+const (
+	parquetString parquetKind = iota
+	parquetInt64
+	parquetDouble
+	parquetBool
+)
+
+func parquetKindOf(databaseType string) parquetKind {
+	typ := strings.ToUpper(databaseType)
+	if strings.HasPrefix(typ, "UNSIGNED ") {
+		// UNSIGNED BIGINT may not fit in INT64.
+		if strings.HasSuffix(typ, "BIGINT") {
+			return parquetString
+		}
+		typ = strings.TrimPrefix(typ, "UNSIGNED ")
+	}
+	switch typ {
+	case "INT2", "INT4", "INT8", "OID", "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "INTEGER", "BIGINT", "YEAR":
+		return parquetInt64
+	case "FLOAT4", "FLOAT8", "FLOAT", "DOUBLE", "REAL":
+		return parquetDouble
+	case "BOOL", "BOOLEAN":
+		return parquetBool
+	}
+	return parquetString
+}
+
+type parquetExport struct {
+	out   io.Writer
+	w     *parquet.Writer
+	kinds []parquetKind
+	names []string
+	// order[i] is the result column stored as parquet column i (parquet
+	// orders the columns of a group by name).
+	order []int
+	row   parquet.Row
+}
+
+func newParquetExport(w io.Writer) *parquetExport {
+	return &parquetExport{out: w}
+}
+
+func (e *parquetExport) start(cols []resultColumn) error {
+	group := parquet.Group{}
+	e.kinds = make([]parquetKind, len(cols))
+	e.names = make([]string, len(cols))
+	for i, c := range cols {
+		// Parquet column names must be unique.
+		name := c.Name
+		for n := 2; group[name] != nil; n++ {
+			name = c.Name + "_" + strconv.Itoa(n)
+		}
+		var node parquet.Node
+		switch e.kinds[i] = parquetKindOf(c.DatabaseType); e.kinds[i] {
+		case parquetInt64:
+			node = parquet.Int(64)
+		case parquetDouble:
+			node = parquet.Leaf(parquet.DoubleType)
+		case parquetBool:
+			node = parquet.Leaf(parquet.BooleanType)
+		default:
+			node = parquet.String()
+		}
+		group[name] = parquet.Optional(parquet.Compressed(node, &parquet.Snappy))
+		e.names[i] = name
+	}
+	e.order = make([]int, len(cols))
+	for i := range e.order {
+		e.order[i] = i
+	}
+	sort.Slice(e.order, func(a, b int) bool { return e.names[e.order[a]] < e.names[e.order[b]] })
+	e.w = parquet.NewWriter(e.out, parquet.NewSchema("result", group))
+	e.row = make(parquet.Row, len(cols))
+	return nil
+}
+
+func (e *parquetExport) write(row []any) error {
+	for col, i := range e.order {
+		v, err := parquetValue(e.kinds[i], row[i])
+		if err != nil {
+			return fmt.Errorf("column %s: %w", e.names[i], err)
+		}
+		def := 1
+		if v.IsNull() {
+			def = 0
+		}
+		e.row[col] = v.Level(0, def, col)
+	}
+	_, err := e.w.WriteRows([]parquet.Row{e.row})
+	return err
+}
+
+func (e *parquetExport) close() error { return e.w.Close() }
+
+func parquetValue(kind parquetKind, v any) (parquet.Value, error) {
+	if v == nil {
+		return parquet.NullValue(), nil
+	}
+	switch kind {
+	case parquetInt64:
+		if n, ok := toInt64(v); ok {
+			return parquet.Int64Value(n), nil
+		}
+		if x, ok := v.(string); ok {
+			if n, err := strconv.ParseInt(x, 10, 64); err == nil {
+				return parquet.Int64Value(n), nil
+			}
+		}
+	case parquetDouble:
+		switch x := v.(type) {
+		case float64:
+			return parquet.DoubleValue(x), nil
+		case float32:
+			return parquet.DoubleValue(float64(x)), nil
+		case string:
+			// encodeFloat returns NaN and infinities as text.
+			if f, err := strconv.ParseFloat(x, 64); err == nil {
+				return parquet.DoubleValue(f), nil
+			}
+		}
+	case parquetBool:
+		if b, ok := v.(bool); ok {
+			return parquet.BooleanValue(b), nil
+		}
+	default:
+		s, err := cellText(v, "")
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.ByteArrayValue([]byte(s)), nil
+	}
+	return parquet.Value{}, fmt.Errorf("cannot store %T in a %s column", v, kindName(kind))
+}
+
+// toInt64 converts any Go integer to int64; drivers return narrow columns as
+// int8, int16, int32 or their unsigned counterparts. It fails for unsigned
+// values above math.MaxInt64.
+func toInt64(v any) (int64, bool) {
+	switch x := v.(type) {
+	case int:
+		return int64(x), true
+	case int8:
+		return int64(x), true
+	case int16:
+		return int64(x), true
+	case int32:
+		return int64(x), true
+	case int64:
+		return x, true
+	case uint:
+		return int64(x), uint64(x) <= math.MaxInt64
+	case uint8:
+		return int64(x), true
+	case uint16:
+		return int64(x), true
+	case uint32:
+		return int64(x), true
+	case uint64:
+		return int64(x), x <= math.MaxInt64
+	}
+	return 0, false
+}
+
+func kindName(kind parquetKind) string {
+	switch kind {
+	case parquetInt64:
+		return "INT64"
+	case parquetDouble:
+		return "DOUBLE"
+	case parquetBool:
+		return "BOOLEAN"
+	}
+	return "STRING"
+}
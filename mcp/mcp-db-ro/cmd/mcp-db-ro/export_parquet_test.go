@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestParquetValue(t *testing.T) {
+	for _, tc := range []struct {
+		kind    parquetKind
+		value   any
+		want    any
+		wantErr string
+	}{
+		{parquetInt64, nil, nil, ""},
+		{parquetInt64, int8(-8), int64(-8), ""},
+		{parquetInt64, int16(-16), int64(-16), ""},
+		{parquetInt64, int32(math.MinInt32), int64(math.MinInt32), ""},
+		{parquetInt64, int64(math.MaxInt64), int64(math.MaxInt64), ""},
+		{parquetInt64, 7, int64(7), ""},
+		{parquetInt64, uint8(8), int64(8), ""},
+		{parquetInt64, uint16(16), int64(16), ""},
+		{parquetInt64, uint32(math.MaxUint32), int64(math.MaxUint32), ""},
+		{parquetInt64, uint64(math.MaxInt64), int64(math.MaxInt64), ""},
+		{parquetInt64, uint64(math.MaxUint64), nil, "cannot store uint64 in a INT64 column"},
+		{parquetInt64, "-42", int64(-42), ""},
+		{parquetInt64, "4.2", nil, "cannot store string"},
+		{parquetInt64, 1.5, nil, "cannot store float64"},
+		{parquetDouble, float32(0.5), 0.5, ""},
+		{parquetDouble, 2.25, 2.25, ""},
+		{parquetDouble, "Infinity", math.Inf(1), ""},
+		{parquetDouble, int32(1), nil, "cannot store int32 in a DOUBLE column"},
+		{parquetBool, true, true, ""},
+		{parquetBool, "true", nil, "cannot store string"},
+		{parquetString, "x", "x", ""},
+		{parquetString, int32(5), "5", ""},
+	} {
+		got, err := parquetValue(tc.kind, tc.value)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("parquetValue(%s, %#v): err %v, want %q", kindName(tc.kind), tc.value, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parquetValue(%s, %#v): %v", kindName(tc.kind), tc.value, err)
+			continue
+		}
+		var v any
+		switch {
+		case got.IsNull():
+		case got.Kind() == parquet.Int64:
+			v = got.Int64()
+		case got.Kind() == parquet.Double:
+			v = got.Double()
+		case got.Kind() == parquet.Boolean:
+			v = got.Boolean()
+		default:
+			v = string(got.ByteArray())
+		}
+		if v != tc.want {
+			t.Errorf("parquetValue(%s, %#v) = %#v, want %#v", kindName(tc.kind), tc.value, v, tc.want)
+		}
+	}
+}
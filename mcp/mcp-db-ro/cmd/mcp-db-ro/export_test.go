@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenameIn(t *testing.T) {
+	dir := t.TempDir()
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+	if err := mkdirAllIn(root, filepath.Join("a", "b")); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, data string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("a/b/out.csv", "old")
+	write("a/b/.out.csv.tmp", "new")
+	if err := renameIn(root, dir, filepath.Join("a", "b", ".out.csv.tmp"), filepath.Join("a", "b", "out.csv")); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "a", "b", "out.csv")); string(b) != "new" {
+		t.Fatalf("out.csv = %q after rename, want new", b)
+	}
+
+	// Swap a directory on the path for a symlink leading outside the root.
+	outside := t.TempDir()
+	if err := os.Mkdir(filepath.Join(outside, "b"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write("a/b/.out.csv.tmp", "newer")
+	if err := os.Rename(filepath.Join(dir, "a"), filepath.Join(dir, "a.moved")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "a")); err != nil {
+		t.Fatal(err)
+	}
+	err = renameIn(root, dir, filepath.Join("a", "b", ".out.csv.tmp"), filepath.Join("a", "b", "out.csv"))
+	if err == nil {
+		t.Fatal("rename through a symlinked directory succeeded")
+	}
+	if !strings.Contains(err.Error(), "escapes") && !strings.Contains(err.Error(), "changed") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(outside, "b")); len(entries) != 0 {
+		t.Errorf("files moved outside the export directory: %v", entries)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// Flavor identifies the server behind a wire-compatible driver kind, e.g.
+// CockroachDB behind postgres or MariaDB behind mysql.
+type Flavor string
+
+const (
+	FlavorPostgres       Flavor = "postgres"
+	FlavorCockroachDB    Flavor = "cockroachdb"
+	FlavorYugabyteDB     Flavor = "yugabytedb"
+	FlavorAuroraPostgres Flavor = "aurora-postgresql"
+	FlavorMySQL          Flavor = "mysql"
+	FlavorMariaDB        Flavor = "mariadb"
+	FlavorTiDB           Flavor = "tidb"
+	FlavorAuroraMySQL    Flavor = "aurora-mysql"
+)
+
+// flavorDetector is implemented by drivers whose kind covers several server
+// flavors.
+type flavorDetector interface {
+	DetectFlavor(ctx context.Context, db *sql.DB) (Flavor, error)
+}
+
+// DetectFlavor reads version(): CockroachDB and YugabyteDB identify
+// themselves there, Aurora only through aurora_version().
+func (postgresDriver) DetectFlavor(ctx context.Context, db *sql.DB) (Flavor, error) {
+	var version string
+	if err := db.QueryRowContext(ctx, `SELECT version()`).Scan(&version); err != nil {
+		return "", err
+	}
+	switch {
+	case strings.Contains(version, "CockroachDB"):
+		return FlavorCockroachDB, nil
+	case strings.Contains(version, "-YB-"):
+		return FlavorYugabyteDB, nil
+	}
+	var aurora string
+	if err := db.QueryRowContext(ctx, `SELECT aurora_version()`).Scan(&aurora); err == nil {
+		return FlavorAuroraPostgres, nil
+	}
+	return FlavorPostgres, nil
+}
+
+// DetectFlavor reads VERSION() and @@version_comment; Aurora only shows up
+// through @@aurora_version.
+func (mysqlDriver) DetectFlavor(ctx context.Context, db *sql.DB) (Flavor, error) {
+	var version, comment string
+	if err := db.QueryRowContext(ctx, `SELECT VERSION(), @@version_comment`).Scan(&version, &comment); err != nil {
+		return "", err
+	}
+	switch s := strings.ToLower(version + " " + comment); {
+	case strings.Contains(s, "tidb"):
+		return FlavorTiDB, nil
+	case strings.Contains(s, "mariadb"):
+		return FlavorMariaDB, nil
+	}
+	var aurora string
+	if err := db.QueryRowContext(ctx, `SELECT @@aurora_version`).Scan(&aurora); err == nil {
+		return FlavorAuroraMySQL, nil
+	}
+	return FlavorMySQL, nil
+}
+
+// flavorDriver returns the driver to use for flavor: a variant that overrides
+// the methods the flavor does not support, or driver itself. Aurora is
+// compatible enough to use the base drivers.
+func flavorDriver(driver DBDriver, flavor Flavor) DBDriver {
+	switch flavor {
+	case FlavorCockroachDB:
+		return cockroachDriver{}
+	case FlavorYugabyteDB:
+		return yugabyteDriver{}
+	case FlavorMariaDB:
+		return mariadbDriver{}
+	case FlavorTiDB:
+		return tidbDriver{}
+	default:
+		return driver
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// EWKB flag bits (PostGIS extended WKB).
+const (
+	ewkbZ    = 0x80000000
+	ewkbM    = 0x40000000
+	ewkbSRID = 0x20000000
+)
+
+var wkbTypeNames = map[uint32]string{
+	1: "Point",
+	2: "LineString",
+	3: "Polygon",
+	4: "MultiPoint",
+	5: "MultiLineString",
+	6: "MultiPolygon",
+	7: "GeometryCollection",
+}
+
+// wkbToGeoJSON converts a WKB or EWKB geometry into a GeoJSON object. srid,
+// if non-zero, overrides the SRID embedded in the geometry. A non-WGS 84 SRID
+// is reported as a named crs member.
+func wkbToGeoJSON(b []byte, srid uint32) (map[string]any, error) {
+	r := &wkbReader{buf: b}
+	g, embedded, err := r.geometry()
+	if err != nil {
+		return nil, err
+	}
+	if r.pos != len(b) {
+		return nil, fmt.Errorf("wkb: %d trailing bytes", len(b)-r.pos)
+	}
+	if srid == 0 {
+		srid = embedded
+	}
+	if srid != 0 && srid != 4326 {
+		g["crs"] = map[string]any{
+			"type":       "name",
+			"properties": map[string]any{"name": fmt.Sprintf("EPSG:%d", srid)},
+		}
+	}
+	return g, nil
+}
+
+type wkbReader struct {
+	buf   []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+func (r *wkbReader) uint32() (uint32, error) {
+	if r.pos+4 > len(r.buf) {
+		return 0, fmt.Errorf("wkb: unexpected end of data")
+	}
+	v := r.order.Uint32(r.buf[r.pos:])
+	r.pos += 4
+	return v, nil
+}
+
+func (r *wkbReader) float64() (float64, error) {
+	if r.pos+8 > len(r.buf) {
+		return 0, fmt.Errorf("wkb: unexpected end of data")
+	}
+	v := math.Float64frombits(r.order.Uint64(r.buf[r.pos:]))
+	r.pos += 8
+	return v, nil
+}
+
+// count reads an element count, rejecting counts that cannot fit in the
+// remaining input (each element needs at least min bytes).
+func (r *wkbReader) count(min int) (int, error) {
+	n, err := r.uint32()
+	if err != nil {
+		return 0, err
+	}
+	if int(n) > (len(r.buf)-r.pos)/min {
+		return 0, fmt.Errorf("wkb: count %d exceeds data", n)
+	}
+	return int(n), nil
+}
+
+func (r *wkbReader) geometry() (map[string]any, uint32, error) {
+	if r.pos >= len(r.buf) {
+		return nil, 0, fmt.Errorf("wkb: unexpected end of data")
+	}
+	switch r.buf[r.pos] {
+	case 0:
+		r.order = binary.BigEndian
+	case 1:
+		r.order = binary.LittleEndian
+	default:
+		return nil, 0, fmt.Errorf("wkb: invalid byte order %d", r.buf[r.pos])
+	}
+	r.pos++
+	typ, err := r.uint32()
+	if err != nil {
+		return nil, 0, err
+	}
+	hasZ, hasM := typ&ewkbZ != 0, typ&ewkbM != 0
+	var srid uint32
+	if typ&ewkbSRID != 0 {
+		if srid, err = r.uint32(); err != nil {
+			return nil, 0, err
+		}
+	}
+	typ &^= ewkbZ | ewkbM | ewkbSRID
+	// ISO WKB encodes Z/M/ZM as +1000/+2000/+3000.
+	switch typ / 1000 {
+	case 1:
+		hasZ = true
+	case 2:
+		hasM = true
+	case 3:
+		hasZ, hasM = true, true
+	}
+	typ %= 1000
+	l := wkbLayout{dims: 2, z: hasZ}
+	if hasZ {
+		l.dims++
+	}
+	if hasM {
+		l.dims++
+	}
+	name, ok := wkbTypeNames[typ]
+	if !ok {
+		return nil, 0, fmt.Errorf("wkb: unsupported geometry type %d", typ)
+	}
+
+	g := map[string]any{"type": name}
+	if typ == 7 {
+		n, err := r.count(5)
+		if err != nil {
+			return nil, 0, err
+		}
+		geoms := make([]any, 0, n)
+		for range n {
+			sub, _, err := r.geometry()
+			if err != nil {
+				return nil, 0, err
+			}
+			geoms = append(geoms, sub)
+		}
+		g["geometries"] = geoms
+		return g, srid, nil
+	}
+	coords, err := r.coordinates(typ, l)
+	if err != nil {
+		return nil, 0, err
+	}
+	g["coordinates"] = coords
+	return g, srid, nil
+}
+
+func (r *wkbReader) coordinates(typ uint32, l wkbLayout) (any, error) {
+	switch typ {
+	case 1:
+		return r.point(l)
+	case 2:
+		return r.points(l)
+	case 3:
+		return r.rings(l)
+	}
+	// Multi* geometries are a count followed by complete WKB geometries.
+	n, err := r.count(5)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]any, 0, n)
+	for range n {
+		sub, _, err := r.geometry()
+		if err != nil {
+			return nil, err
+		}
+		if want := wkbTypeNames[typ-3]; sub["type"] != want {
+			return nil, fmt.Errorf("wkb: %s in %s", sub["type"], wkbTypeNames[typ])
+		}
+		out = append(out, sub["coordinates"])
+	}
+	return out, nil
+}
+
+// wkbLayout is the coordinate layout of a geometry: 2 to 4 ordinates, of which
+// the third is z if z is set (otherwise m).
+type wkbLayout struct {
+	dims int
+	z    bool
+}
+
+func (r *wkbReader) point(l wkbLayout) ([]float64, error) {
+	p := make([]float64, l.dims)
+	for i := range p {
+		v, err := r.float64()
+		if err != nil {
+			return nil, err
+		}
+		p[i] = v
+	}
+	// POINT EMPTY is encoded as NaN ordinates, which JSON cannot represent.
+	if math.IsNaN(p[0]) {
+		return []float64{}, nil
+	}
+	// GeoJSON has no M axis; keep x, y and (if present) z.
+	if l.z {
+		return p[:3], nil
+	}
+	return p[:2], nil
+}
+
+func (r *wkbReader) points(l wkbLayout) ([][]float64, error) {
+	n, err := r.count(8 * l.dims)
+	if err != nil {
+		return nil, err
+	}
+	out := make([][]float64, 0, n)
+	for range n {
+		p, err := r.point(l)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func (r *wkbReader) rings(l wkbLayout) ([][][]float64, error) {
+	n, err := r.count(4)
+	if err != nil {
+		return nil, err
+	}
+	out := make([][][]float64, 0, n)
+	for range n {
+		ring, err := r.points(l)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ring)
+	}
+	return out, nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Connection states reported by db.listConnections.
+const (
+	stateIdle     = "idle"     // not used yet
+	stateOK       = "ok"       // the last check or call succeeded
+	stateDegraded = "degraded" // was ok, a few recent failures
+	stateDown     = "down"     // never reached, or failing persistently
+)
+
+const (
+	connectTimeout = 5 * time.Second
+	backoffMin     = time.Second
+	backoffMax     = time.Minute
+	// downAfter is the number of consecutive failures after which a
+	// connection that has worked before counts as down.
+	downAfter = 3
+)
+
+// connHealth tracks whether a connection is reachable. Connections are
+// checked on first use rather than at startup; after a failure the next check
+// waits for an exponentially growing backoff, and calls in between fail fast
+// while the connection is down.
+type connHealth struct {
+	// checkMu serializes checks so concurrent calls share one ping.
+	checkMu sync.Mutex
+
+	mu        sync.Mutex
+	state     string
+	since     time.Time // of the last state change
+	reached   bool      // a check has succeeded once (the flavor is known)
+	failures  int       // consecutive
+	lastErr   error
+	lastErrAt time.Time
+	retryAt   time.Time
+}
+
+// ready makes sure c is usable: it pings the server on first use and when a
+// failed connection is due for a retry.
+func (c *dbClient) ready(ctx context.Context) error {
+	h := &c.health
+	if !c.needsCheck() {
+		return nil
+	}
+	h.checkMu.Lock()
+	defer h.checkMu.Unlock()
+	// Another call may have checked in the meantime.
+	if !c.needsCheck() {
+		return nil
+	}
+	h.mu.Lock()
+	state, retryAt, lastErr := h.state, h.retryAt, h.lastErr
+	h.mu.Unlock()
+	if state == stateDown && time.Now().Before(retryAt) {
+		return &unavailableError{
+			msg: fmt.Sprintf("connection %s is down, next attempt in %s", c.cfg.Name, time.Until(retryAt).Round(time.Second)),
+			err: lastErr,
+		}
+	}
+
+	err := c.check(ctx)
+	c.recordResult(err)
+	if err != nil {
+		return &unavailableError{msg: "connection " + c.cfg.Name, err: err}
+	}
+	return nil
+}
+
+// unavailableError is returned by ready; it has been recorded already.
+type unavailableError struct {
+	msg string
+	err error
+}
+
+func (e *unavailableError) Error() string { return e.msg + ": " + e.err.Error() }
+func (e *unavailableError) Unwrap() error { return e.err }
+
+// needsCheck reports whether ready has to ping: before first use, and once
+// the backoff of a degraded or down connection has passed. A degraded
+// connection is still used until then.
+func (c *dbClient) needsCheck() bool {
+	h := &c.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	switch h.state {
+	case stateOK:
+		return false
+	case stateDegraded:
+		return !time.Now().Before(h.retryAt)
+	default:
+		return true
+	}
+}
+
+// check pings the server; the first successful ping also detects the server
+// flavor.
+func (c *dbClient) check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+	if c.docs != nil {
+		if err := c.docs.Ping(ctx); err != nil {
+			return fmt.Errorf("ping: %w", err)
+		}
+		return nil
+	}
+	if err := c.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+	c.health.mu.Lock()
+	reached := c.health.reached
+	c.health.mu.Unlock()
+	if !reached {
+		flavor, driver := detectFlavor(ctx, c.logger, c.cfg.Name, c.db, c.driver)
+		c.health.mu.Lock()
+		c.flavor, c.driver = flavor, driver
+		c.health.mu.Unlock()
+	}
+	return nil
+}
+
+// recordResult updates the state after a check or a call that failed with a
+// connection error (err) or succeeded (nil).
+func (c *dbClient) recordResult(err error) {
+	h := &c.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	prev := h.state
+	now := time.Now()
+	if err == nil {
+		h.state, h.reached, h.failures, h.retryAt = stateOK, true, 0, time.Time{}
+	} else {
+		h.failures++
+		h.lastErr, h.lastErrAt = err, now
+		h.retryAt = now.Add(backoff(h.failures))
+		h.state = stateDown
+		if h.reached && h.failures < downAfter {
+			h.state = stateDegraded
+		}
+	}
+	if h.state != prev {
+		h.since = now
+		if c.logger != nil && (prev != "" || h.state != stateOK) {
+			if err != nil {
+				c.logger.Printf("connection %s: %s: %v", c.cfg.Name, h.state, err)
+			} else {
+				c.logger.Printf("connection %s: %s", c.cfg.Name, h.state)
+			}
+		}
+	}
+}
+
+// backoff is the wait before the next check after n consecutive failures.
+func backoff(n int) time.Duration {
+	d := backoffMin
+	for i := 1; i < n && d < backoffMax; i++ {
+		d *= 2
+	}
+	return min(d, backoffMax)
+}
+
+// observe feeds the outcome of a tool call on c into its state: connection
+// errors count as failures, and any success clears them.
+func (c *dbClient) observe(err error) {
+	var unavailable *unavailableError
+	switch {
+	case errors.As(err, &unavailable):
+	case err == nil:
+		if c.needsRecovery() {
+			c.recordResult(nil)
+		}
+	case isConnError(err):
+		c.recordResult(err)
+	}
+}
+
+func (c *dbClient) needsRecovery() bool {
+	c.health.mu.Lock()
+	defer c.health.mu.Unlock()
+	return c.health.state == stateDegraded
+}
+
+// isConnError reports whether err means the server could not be reached, as
+// opposed to an error in the statement.
+func isConnError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.As(err, &netErr) && !netErr.Timeout() ||
+		mongo.IsNetworkError(err)
+}
+
+// healthStatus reports the state for db.listConnections.
+func (c *dbClient) healthStatus() map[string]any {
+	h := &c.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	state := h.state
+	if state == "" {
+		state = stateIdle
+	}
+	out := map[string]any{"state": state}
+	if !h.since.IsZero() {
+		out["since"] = h.since.UTC().Format(time.RFC3339)
+	}
+	if h.lastErr != nil {
+		out["lastError"] = h.lastErr.Error()
+		out["lastErrorAt"] = h.lastErrAt.UTC().Format(time.RFC3339)
+	}
+	if state == stateDown || state == stateDegraded {
+		out["retryAt"] = h.retryAt.UTC().Format(time.RFC3339)
+	}
+	return out
+}
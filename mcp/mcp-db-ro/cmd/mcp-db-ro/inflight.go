@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+// requestIDMetaKey is stamped into a tool call's _meta by the before-call hook,
+// because mcp-go does not hand the JSON-RPC request id to tool handlers.
+const requestIDMetaKey = "mcp-db-ro/requestId"
+
+const progressInterval = time.Second
+
+// requestTracker maps in-flight tool calls to their cancel funcs so that a
+// client's notifications/cancelled aborts the running SQL.
+type requestTracker struct {
+	mu       sync.Mutex
+	inflight map[string]context.CancelFunc
+}
+
+func newRequestTracker() *requestTracker {
+	return &requestTracker{inflight: map[string]context.CancelFunc{}}
+}
+
+// requestKey identifies a request within its session. The id is normalized:
+// the hook gets an mcp.RequestId, while the id in notifications/cancelled is
+// decoded from JSON (a float64 for numbers).
+func requestKey(ctx context.Context, id any) string {
+	session := ""
+	if cs := mcpserver.ClientSessionFromContext(ctx); cs != nil {
+		session = cs.SessionID()
+	}
+	switch v := id.(type) {
+	case mcp.RequestId:
+		return session + "/" + v.String()
+	case *mcp.RequestId:
+		return session + "/" + v.String()
+	default:
+		return session + "/" + mcp.NewRequestId(v).String()
+	}
+}
+
+// stamp is registered as a before-call-tool hook.
+func (t *requestTracker) stamp(_ context.Context, id any, req *mcp.CallToolRequest) {
+	if id == nil {
+		return
+	}
+	if req.Params.Meta == nil {
+		req.Params.Meta = &mcp.Meta{}
+	}
+	if req.Params.Meta.AdditionalFields == nil {
+		req.Params.Meta.AdditionalFields = map[string]any{}
+	}
+	req.Params.Meta.AdditionalFields[requestIDMetaKey] = id
+}
+
+// begin returns a context that is cancelled when the client cancels req. The
+// returned func must be called when the tool call completes.
+func (t *requestTracker) begin(ctx context.Context, req mcp.CallToolRequest) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	if req.Params.Meta == nil || req.Params.Meta.AdditionalFields[requestIDMetaKey] == nil {
+		return ctx, cancel
+	}
+	key := requestKey(ctx, req.Params.Meta.AdditionalFields[requestIDMetaKey])
+	t.mu.Lock()
+	t.inflight[key] = cancel
+	t.mu.Unlock()
+	return ctx, func() {
+		t.mu.Lock()
+		delete(t.inflight, key)
+		t.mu.Unlock()
+		cancel()
+	}
+}
+
+// handleCancelled is registered for notifications/cancelled.
+func (t *requestTracker) handleCancelled(ctx context.Context, n mcp.JSONRPCNotification) {
+	id, ok := n.Params.AdditionalFields["requestId"]
+	if !ok || id == nil {
+		return
+	}
+	key := requestKey(ctx, id)
+	t.mu.Lock()
+	cancel := t.inflight[key]
+	t.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+type progressKey struct{}
+
+// queryProgress counts rows fetched by the current tool call.
+type queryProgress struct {
+	rows atomic.Int64
+}
+
+func progressFromContext(ctx context.Context) *queryProgress {
+	p, _ := ctx.Value(progressKey{}).(*queryProgress)
+	return p
+}
+
+// addRow records one fetched row, if the caller asked for progress.
+func (p *queryProgress) addRow() {
+	if p != nil {
+		p.rows.Add(1)
+	}
+}
+
+// startProgress emits notifications/progress (rows fetched so far and elapsed
+// time) every progressInterval while the call runs, if the client supplied a
+// progress token. The returned func stops reporting.
+func startProgress(ctx context.Context, req mcp.CallToolRequest) (context.Context, func()) {
+	if req.Params.Meta == nil || req.Params.Meta.ProgressToken == nil {
+		return ctx, func() {}
+	}
+	srv := mcpserver.ServerFromContext(ctx)
+	if srv == nil {
+		return ctx, func() {}
+	}
+	token := req.Params.Meta.ProgressToken
+	p := &queryProgress{}
+	ctx = context.WithValue(ctx, progressKey{}, p)
+
+	start := time.Now()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				rows := p.rows.Load()
+				elapsed := time.Since(start).Round(time.Millisecond)
+				// progress must increase with every notification, so it carries
+				// elapsed seconds; the row count goes into the message.
+				_ = srv.SendNotificationToClient(ctx, "notifications/progress", map[string]any{
+					"progressToken": token,
+					"progress":      elapsed.Seconds(),
+					"message":       fmt.Sprintf("%d rows fetched, %s elapsed", rows, elapsed),
+				})
+			}
+		}
+	}()
+	return ctx, func() {
+		close(done)
+		<-stopped
+	}
+}
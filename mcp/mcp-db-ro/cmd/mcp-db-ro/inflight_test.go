@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestCancelledNotificationCancelsCall(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		callID string // JSON id of the tools/call request
+	}{
+		{"number", `5`},
+		{"string", `"req-7"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var id mcp.RequestId
+			if err := json.Unmarshal([]byte(tc.callID), &id); err != nil {
+				t.Fatal(err)
+			}
+			tracker := newRequestTracker()
+			req := mcp.CallToolRequest{}
+			tracker.stamp(context.Background(), id, &req)
+			ctx, done := tracker.begin(context.Background(), req)
+			defer done()
+
+			var n mcp.JSONRPCNotification
+			raw := `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":` + tc.callID + `,"reason":"user"}}`
+			if err := json.Unmarshal([]byte(raw), &n); err != nil {
+				t.Fatal(err)
+			}
+			tracker.handleCancelled(context.Background(), n)
+			if ctx.Err() == nil {
+				t.Fatalf("call %s was not cancelled", tc.callID)
+			}
+		})
+	}
+}
+
+func TestCancelledNotificationIgnoresOtherCalls(t *testing.T) {
+	tracker := newRequestTracker()
+	req := mcp.CallToolRequest{}
+	tracker.stamp(context.Background(), mcp.NewRequestId(int64(1)), &req)
+	ctx, done := tracker.begin(context.Background(), req)
+
+	var n mcp.JSONRPCNotification
+	if err := json.Unmarshal([]byte(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":2}}`), &n); err != nil {
+		t.Fatal(err)
+	}
+	tracker.handleCancelled(context.Background(), n)
+	if ctx.Err() != nil {
+		t.Fatal("a different call was cancelled")
+	}
+	done()
+	if len(tracker.inflight) != 0 {
+		t.Fatalf("%d calls left registered after completion", len(tracker.inflight))
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"log"
 	"os"
 
+	_ "github.com/ClickHouse/clickhouse-go/v2"
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/microsoft/go-mssqldb"
+	_ "modernc.org/sqlite"
 )
 
 func main() {
@@ -34,7 +37,7 @@ func main() {
 	}
 	defer s.close()
 
-	if err := runMCP(s); err != nil {
+	if err := runMCP(s, dbConfigPath); err != nil {
 		fmt.Fprintln(os.Stderr, "server error:", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// mariadbDriver is mysqlDriver for MariaDB, which has its own statement
+// timeout variable and no EXPLAIN FORMAT=TREE.
+type mariadbDriver struct{ mysqlDriver }
+
+func (mariadbDriver) Explain(ctx context.Context, db queryer, query, format string, args ...any) ([]map[string]any, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "text", "traditional":
+		return queryAll(ctx, db, "EXPLAIN "+query, args...)
+	case "json":
+		return queryAll(ctx, db, "EXPLAIN FORMAT=JSON "+query, args...)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s (mariadb supports: text, json)", format)
+	}
+}
+
+// SetStatementTimeout uses max_statement_time (in seconds), which unlike
+// MySQL's max_execution_time applies to every statement.
+func (mariadbDriver) SetStatementTimeout(ctx context.Context, q queryer, timeout time.Duration, _ bool) error {
+	_, err := q.ExecContext(ctx, fmt.Sprintf("SET SESSION max_statement_time = %.3f", timeout.Seconds()))
+	return err
+}
@@ -2,58 +2,97 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	mcpserver "github.com/mark3labs/mcp-go/server"
 )
 
-func runMCP(db *dbService) error {
+// runMCP serves the tools over stdio. If configPath is set the config is
+// reloaded when it changes, and clients are notified of each reload.
+func runMCP(db *dbService, configPath string) error {
+	tracker := newRequestTracker()
+	hooks := &mcpserver.Hooks{}
+	hooks.AddBeforeCallTool(tracker.stamp)
+	hooks.AddOnUnregisterSession(func(_ context.Context, session mcpserver.ClientSession) {
+		db.endSession(session.SessionID())
+	})
+
 	s := mcpserver.NewMCPServer("mcp-db-ro", "0.1.0",
-		mcpserver.WithToolCapabilities(false),
+		mcpserver.WithToolCapabilities(true),
+		mcpserver.WithLogging(),
 		mcpserver.WithRecovery(),
+		mcpserver.WithHooks(hooks),
 	)
+	s.AddNotificationHandler("notifications/cancelled", tracker.handleCancelled)
+
+	// Helper to wrap handlers: the request context is cancelled by
+	// notifications/cancelled, carries the MCP session and progress
+	// reporting when requested.
+	// Formatted tools render their text content in the format argument.
+	handle := func(formatted bool, fn func(context.Context, mcp.CallToolRequest) (any, error)) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			format := formatJSON
+			if formatted {
+				format = req.GetString("format", formatJSON)
+				if err := checkFormat(format); err != nil {
+					return toolError(err), nil
+				}
+			}
+			ctx, done := tracker.begin(ctx, req)
+			defer done()
+			if session := mcpserver.ClientSessionFromContext(ctx); session != nil {
+				ctx = withSession(ctx, session.SessionID())
+			}
+			defer db.beginCall()()
+			ctx, stopProgress := startProgress(ctx, req)
+			defer stopProgress()
 
-	// Helper to wrap handlers
-	wrap := func(fn func(mcp.CallToolRequest) (any, error)) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return func(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			out, err := fn(req)
+			out, err := fn(ctx, req)
+			db.observe(req.GetString("connection", ""), err)
 			if err != nil {
-				return mcp.NewToolResultError(err.Error()), nil
+				return toolError(err), nil
 			}
-			return toolJSON(out)
+			return toolResult(out, format)
 		}
 	}
+	wrap := func(fn func(context.Context, mcp.CallToolRequest) (any, error)) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return handle(false, fn)
+	}
+	wrapFormatted := func(fn func(context.Context, mcp.CallToolRequest) (any, error)) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return handle(true, fn)
+	}
 
-	s.AddTool(toolListConnections(), wrap(func(_ mcp.CallToolRequest) (any, error) {
+	s.AddTool(toolListConnections(), wrapFormatted(func(_ context.Context, _ mcp.CallToolRequest) (any, error) {
 		return db.listConnections(), nil
 	}))
 
-	s.AddTool(toolListDatabases(), wrap(func(req mcp.CallToolRequest) (any, error) {
+	s.AddTool(toolListDatabases(), wrapFormatted(func(ctx context.Context, req mcp.CallToolRequest) (any, error) {
 		conn, err := req.RequireString("connection")
 		if err != nil {
 			return nil, err
 		}
-		return db.listDatabases(conn)
+		return db.listDatabases(ctx, conn)
 	}))
 
-	s.AddTool(toolListSchemas(), wrap(func(req mcp.CallToolRequest) (any, error) {
+	s.AddTool(toolListSchemas(), wrapFormatted(func(ctx context.Context, req mcp.CallToolRequest) (any, error) {
 		conn, err := req.RequireString("connection")
 		if err != nil {
 			return nil, err
 		}
-		return db.listSchemas(conn, req.GetString("database", ""))
+		return db.listSchemas(ctx, conn, req.GetString("database", ""))
 	}))
 
-	s.AddTool(toolListTables(), wrap(func(req mcp.CallToolRequest) (any, error) {
+	s.AddTool(toolListTables(), wrapFormatted(func(ctx context.Context, req mcp.CallToolRequest) (any, error) {
 		conn, err := req.RequireString("connection")
 		if err != nil {
 			return nil, err
 		}
-		return db.listTables(conn, req.GetString("database", ""), req.GetString("schema", ""))
+		return db.listTables(ctx, conn, req.GetString("database", ""), req.GetString("schema", ""))
 	}))
 
-	s.AddTool(toolDescribeTable(), wrap(func(req mcp.CallToolRequest) (any, error) {
+	s.AddTool(toolDescribeTable(), wrapFormatted(func(ctx context.Context, req mcp.CallToolRequest) (any, error) {
 		conn, err := req.RequireString("connection")
 		if err != nil {
 			return nil, err
@@ -62,10 +101,10 @@ func runMCP(db *dbService) error {
 		if err != nil {
 			return nil, err
 		}
-		return db.describeTable(conn, req.GetString("database", ""), req.GetString("schema", ""), table)
+		return db.describeTable(ctx, conn, req.GetString("database", ""), req.GetString("schema", ""), table, req.GetInt("sampleSize", 0))
 	}))
 
-	s.AddTool(toolListIndexes(), wrap(func(req mcp.CallToolRequest) (any, error) {
+	s.AddTool(toolListIndexes(), wrapFormatted(func(ctx context.Context, req mcp.CallToolRequest) (any, error) {
 		conn, err := req.RequireString("connection")
 		if err != nil {
 			return nil, err
@@ -74,10 +113,10 @@ func runMCP(db *dbService) error {
 		if err != nil {
 			return nil, err
 		}
-		return db.listIndexes(conn, req.GetString("database", ""), req.GetString("schema", ""), table)
+		return db.listIndexes(ctx, conn, req.GetString("database", ""), req.GetString("schema", ""), table)
 	}))
 
-	s.AddTool(toolTablePartitions(), wrap(func(req mcp.CallToolRequest) (any, error) {
+	s.AddTool(toolTablePartitions(), wrapFormatted(func(ctx context.Context, req mcp.CallToolRequest) (any, error) {
 		conn, err := req.RequireString("connection")
 		if err != nil {
 			return nil, err
@@ -86,10 +125,10 @@ func runMCP(db *dbService) error {
 		if err != nil {
 			return nil, err
 		}
-		return db.tablePartitions(conn, req.GetString("database", ""), req.GetString("schema", ""), table)
+		return db.tablePartitions(ctx, conn, req.GetString("database", ""), req.GetString("schema", ""), table)
 	}))
 
-	s.AddTool(toolExplain(), wrap(func(req mcp.CallToolRequest) (any, error) {
+	s.AddTool(toolExplain(), wrap(func(ctx context.Context, req mcp.CallToolRequest) (any, error) {
 		conn, err := req.RequireString("connection")
 		if err != nil {
 			return nil, err
@@ -98,10 +137,41 @@ func runMCP(db *dbService) error {
 		if err != nil {
 			return nil, err
 		}
-		return db.explain(conn, req.GetString("database", ""), query, req.GetString("format", ""))
+		params, err := parseQueryParams(req.GetArguments()["params"])
+		if err != nil {
+			return nil, err
+		}
+		return db.explain(ctx, conn, req.GetString("database", ""), query, req.GetString("format", ""), params)
 	}))
 
-	s.AddTool(toolQuery(), wrap(func(req mcp.CallToolRequest) (any, error) {
+	s.AddTool(toolQuery(), wrapFormatted(func(ctx context.Context, req mcp.CallToolRequest) (any, error) {
+		conn, err := req.RequireString("connection")
+		if err != nil {
+			return nil, err
+		}
+		cursor := req.GetString("cursor", "")
+		query := req.GetString("query", "")
+		if cursor == "" {
+			if query, err = req.RequireString("query"); err != nil {
+				return nil, err
+			}
+		}
+		params, err := parseQueryParams(req.GetArguments()["params"])
+		if err != nil {
+			return nil, err
+		}
+		var objects bool
+		switch rowFormat := req.GetString("rowFormat", "arrays"); rowFormat {
+		case "arrays":
+		case "objects":
+			objects = true
+		default:
+			return nil, fmt.Errorf("unsupported rowFormat: %s (use arrays or objects)", rowFormat)
+		}
+		return db.query(ctx, conn, req.GetString("database", ""), query, params, req.GetInt("limit", 200), cursor, objects)
+	}))
+
+	s.AddTool(toolExportQuery(), wrap(func(ctx context.Context, req mcp.CallToolRequest) (any, error) {
 		conn, err := req.RequireString("connection")
 		if err != nil {
 			return nil, err
@@ -110,10 +180,15 @@ func runMCP(db *dbService) error {
 		if err != nil {
 			return nil, err
 		}
-		return db.query(conn, req.GetString("database", ""), query, req.GetInt("limit", 200))
+		params, err := parseQueryParams(req.GetArguments()["params"])
+		if err != nil {
+			return nil, err
+		}
+		return db.exportQuery(ctx, conn, req.GetString("database", ""), query, params,
+			req.GetString("format", ""), req.GetString("path", ""), req.GetBool("overwrite", false))
 	}))
 
-	s.AddTool(toolGetDDL(), wrap(func(req mcp.CallToolRequest) (any, error) {
+	s.AddTool(toolGetDDL(), wrap(func(ctx context.Context, req mcp.CallToolRequest) (any, error) {
 		conn, err := req.RequireString("connection")
 		if err != nil {
 			return nil, err
@@ -122,10 +197,51 @@ func runMCP(db *dbService) error {
 		if err != nil {
 			return nil, err
 		}
-		return db.getDDL(conn, req.GetString("database", ""), req.GetString("schema", ""), table, req.GetBool("includeIndexes", false))
+		return db.getDDL(ctx, conn, req.GetString("database", ""), req.GetString("schema", ""), table, req.GetBool("includeIndexes", false))
+	}))
+
+	s.AddTool(toolFind(), wrapFormatted(func(ctx context.Context, req mcp.CallToolRequest) (any, error) {
+		conn, err := req.RequireString("connection")
+		if err != nil {
+			return nil, err
+		}
+		collection, err := req.RequireString("collection")
+		if err != nil {
+			return nil, err
+		}
+		args := req.GetArguments()
+		var q findQuery
+		if q.Filter, err = parseMongoDoc("filter", args["filter"]); err != nil {
+			return nil, err
+		}
+		if q.Projection, err = parseMongoDoc("projection", args["projection"]); err != nil {
+			return nil, err
+		}
+		if q.Sort, err = parseMongoDoc("sort", args["sort"]); err != nil {
+			return nil, err
+		}
+		q.Skip = int64(req.GetInt("skip", 0))
+		q.Limit = req.GetInt("limit", 200)
+		return db.find(ctx, conn, req.GetString("database", ""), collection, q)
+	}))
+
+	s.AddTool(toolAggregate(), wrapFormatted(func(ctx context.Context, req mcp.CallToolRequest) (any, error) {
+		conn, err := req.RequireString("connection")
+		if err != nil {
+			return nil, err
+		}
+		collection, err := req.RequireString("collection")
+		if err != nil {
+			return nil, err
+		}
+		pipeline, err := parseMongoPipeline(req.GetArguments()["pipeline"])
+		if err != nil {
+			return nil, err
+		}
+		return db.aggregate(ctx, conn, req.GetString("database", ""), collection, pipeline, req.GetInt("limit", 200))
 	}))
 
-	s.AddTool(toolUseDatabase(), wrap(func(req mcp.CallToolRequest) (any, error) {
+	s.AddTool(toolUseDatabase(), wrap(func(ctx context.Context, req mcp.CallToolRequest) (any, error) {
 		conn, err := req.RequireString("connection")
 		if err != nil {
 			return nil, err
@@ -134,14 +250,54 @@ func runMCP(db *dbService) error {
 		if err != nil {
 			return nil, err
 		}
-		return db.useDatabase(conn, database)
+		return db.useDatabase(ctx, conn, database)
+	}))
+
+	s.AddTool(toolCurrentContext(), wrapFormatted(func(ctx context.Context, req mcp.CallToolRequest) (any, error) {
+		return db.currentContext(ctx, req.GetString("connection", ""))
+	}))
+
+	s.AddTool(toolPoolStats(), wrap(func(_ context.Context, req mcp.CallToolRequest) (any, error) {
+		return db.poolStats(req.GetString("connection", ""))
 	}))
 
+	if configPath != "" {
+		stop := watchConfig(db, configPath, func(sum reloadSummary, err error) {
+			notifyReload(s, db, sum, err)
+		})
+		defer stop()
+	}
+
 	return mcpserver.ServeStdio(s)
 }
 
-func toolJSON(v any) (*mcp.CallToolResult, error) {
-	pretty, err := json.MarshalIndent(v, "", "  ")
+// notifyReload logs the outcome of a config reload and, unless nothing
+// changed, sends it to clients as a log message. A successful reload is
+// followed by tools/list_changed so clients refresh what they know about the
+// connections.
+func notifyReload(s *mcpserver.MCPServer, db *dbService, sum reloadSummary, err error) {
+	level, msg := mcp.LoggingLevelInfo, sum.String()
+	if err != nil {
+		level, msg = mcp.LoggingLevelError, "config reload failed, keeping the current connections: "+err.Error()
+	}
+	db.logger.Print(msg)
+	if err == nil && sum.empty() {
+		return
+	}
+	s.SendNotificationToAllClients("notifications/message", map[string]any{
+		"level":  level,
+		"logger": "mcp-db-ro",
+		"data":   map[string]any{"message": msg, "reload": sum},
+	})
+	if err == nil {
+		s.SendNotificationToAllClients(mcp.MethodNotificationToolsListChanged, nil)
+	}
+}
+
+// toolResult returns v as structured content, with the text content rendered
+// in format.
+func toolResult(v any, format string) (*mcp.CallToolResult, error) {
+	text, err := renderText(v, format)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -152,12 +308,25 @@ func toolJSON(v any) (*mcp.CallToolResult, error) {
 	case []string, []map[string]any, []any:
 		structured = map[string]any{"data": v}
 	}
-	return mcp.NewToolResultStructured(structured, string(pretty)), nil
+	return mcp.NewToolResultStructured(structured, text), nil
+}
+
+// toolError reports err as a tool-level error. Queries rejected by the
+// read-only guard also carry the structured block reason.
+func toolError(err error) *mcp.CallToolResult {
+	var blocked *sqlBlockError
+	if errors.As(err, &blocked) {
+		res := mcp.NewToolResultStructured(map[string]any{"blocked": blocked}, err.Error())
+		res.IsError = true
+		return res
+	}
+	return mcp.NewToolResultError(err.Error())
 }
 
 func toolListConnections() mcp.Tool {
 	return mcp.NewTool("db.listConnections",
-		mcp.WithDescription("List configured connection names."),
+		mcp.WithDescription("List configured connections with their driver and status (ok, degraded, down or idle, with the last error) and, for postgres and mysql, the detected server flavor (e.g. cockroachdb, mariadb, tidb), the state of their SSH tunnel and certificate expiry, if any."),
+		withResultFormat(),
 	)
 }
 
@@ -165,14 +334,16 @@ func toolListDatabases() mcp.Tool {
 	return mcp.NewTool("db.listDatabases",
 		mcp.WithDescription("List databases."),
 		mcp.WithString("connection", mcp.Required(), mcp.Description("Configured connection name")),
+		withResultFormat(),
 	)
 }
 
 func toolListSchemas() mcp.Tool {
 	return mcp.NewTool("db.listSchemas",
-		mcp.WithDescription("List schemas (Postgres returns schemas; MySQL returns empty)."),
+		mcp.WithDescription("List schemas (Postgres, SQL Server and DuckDB return schemas; MySQL and SQLite return empty)."),
 		mcp.WithString("connection", mcp.Required(), mcp.Description("Configured connection name")),
-		mcp.WithString("database", mcp.Description("Database name (Postgres). If omitted uses selected/default database.")),
+		mcp.WithString("database", mcp.Description("Database name (Postgres/SQL Server). If omitted uses selected/default database.")),
+		withResultFormat(),
 	)
 }
 
@@ -181,7 +352,8 @@ func toolListTables() mcp.Tool {
 		mcp.WithDescription("List tables."),
 		mcp.WithString("connection", mcp.Required(), mcp.Description("Configured connection name")),
 		mcp.WithString("database", mcp.Description("Database name (MySQL/Postgres). If omitted uses selected/default; MySQL can also list across all DBs (limited) if none selected.")),
-		mcp.WithString("schema", mcp.Description("Schema name (Postgres, default public)")),
+		mcp.WithString("schema", mcp.Description("Schema name (Postgres, default public; SQL Server, default dbo; DuckDB, default main)")),
+		withResultFormat(),
 	)
 }
 
@@ -190,8 +362,10 @@ func toolDescribeTable() mcp.Tool {
 		mcp.WithDescription("Describe table columns."),
 		mcp.WithString("connection", mcp.Required(), mcp.Description("Configured connection name")),
 		mcp.WithString("database", mcp.Description("Database name (MySQL required unless selected/default is set; Postgres uses selected/default if omitted).")),
-		mcp.WithString("schema", mcp.Description("Schema name (Postgres, default public)")),
-		mcp.WithString("table", mcp.Required(), mcp.Description("Table name")),
+		mcp.WithString("schema", mcp.Description("Schema name (Postgres, default public; SQL Server, default dbo; DuckDB, default main)")),
+		mcp.WithString("table", mcp.Required(), mcp.Description("Table name (MongoDB: collection)")),
+		mcp.WithNumber("sampleSize", mcp.Description("MongoDB: number of sampled documents the schema is inferred from (default 100, max 10000)")),
+		withResultFormat(),
 	)
 }
 
@@ -200,8 +374,9 @@ func toolListIndexes() mcp.Tool {
 		mcp.WithDescription("List indexes of a table."),
 		mcp.WithString("connection", mcp.Required(), mcp.Description("Configured connection name")),
 		mcp.WithString("database", mcp.Description("Database name (MySQL required unless selected/default is set; Postgres uses selected/default if omitted).")),
-		mcp.WithString("schema", mcp.Description("Schema name (Postgres, default public)")),
+		mcp.WithString("schema", mcp.Description("Schema name (Postgres, default public; SQL Server, default dbo; DuckDB, default main)")),
 		mcp.WithString("table", mcp.Required(), mcp.Description("Table name")),
+		withResultFormat(),
 	)
 }
 
@@ -210,28 +385,62 @@ func toolTablePartitions() mcp.Tool {
 		mcp.WithDescription("Inspect physical partitions (best effort)."),
 		mcp.WithString("connection", mcp.Required(), mcp.Description("Configured connection name")),
 		mcp.WithString("database", mcp.Description("Database name (MySQL required unless selected/default is set; Postgres uses selected/default if omitted).")),
-		mcp.WithString("schema", mcp.Description("Schema name (Postgres, default public)")),
+		mcp.WithString("schema", mcp.Description("Schema name (Postgres, default public; SQL Server, default dbo; DuckDB, default main)")),
 		mcp.WithString("table", mcp.Required(), mcp.Description("Table name")),
+		withResultFormat(),
 	)
 }
 
 func toolExplain() mcp.Tool {
 	return mcp.NewTool("db.explain",
-		mcp.WithDescription("Explain a query (DB-native EXPLAIN)."),
+		mcp.WithDescription("Explain a query (DB-native EXPLAIN). MongoDB: query is a find, aggregate, count or distinct command in Extended JSON."),
 		mcp.WithString("connection", mcp.Required(), mcp.Description("Configured connection name")),
 		mcp.WithString("database", mcp.Description("Database name. MySQL: can specify any accessible database; Postgres: uses selected/default if omitted.")),
 		mcp.WithString("query", mcp.Required(), mcp.Description("Query to explain")),
-		mcp.WithString("format", mcp.Description("Postgres: text|json; MySQL ignores")),
+		mcp.WithString("format", mcp.Description("Postgres/DuckDB: text|json; CockroachDB: text|verbose|opt; MySQL: text|json|tree; MariaDB: text|json; TiDB: text|brief|dot|json; SQL Server: xml; ClickHouse: plan|pipeline|estimate; MongoDB: queryPlanner|executionStats|allPlansExecution; SQLite ignores")),
+		withQueryParams(),
 	)
 }
 
 func toolQuery() mcp.Tool {
 	return mcp.NewTool("db.query",
-		mcp.WithDescription("Run a read-only query (SELECT/WITH/SHOW/EXPLAIN)."),
+		mcp.WithDescription("Run a single read-only query (SELECT/WITH/VALUES/TABLE/SHOW/EXPLAIN). Data-modifying CTEs, SELECT INTO, locking clauses and multiple statements are rejected."),
 		mcp.WithString("connection", mcp.Required(), mcp.Description("Configured connection name")),
 		mcp.WithString("database", mcp.Description("Database name. MySQL: can specify any accessible database; Postgres: uses selected/default if omitted. If not specified and no default configured, MySQL will fail.")),
-		mcp.WithString("query", mcp.Required(), mcp.Description("Query to execute")),
-		mcp.WithNumber("limit", mcp.Description("Row limit applied client-side (default 200)")),
+		mcp.WithString("query", mcp.Description("Query to execute (required unless cursor is given)")),
+		withQueryParams(),
+		mcp.WithNumber("limit", mcp.Description("Page size (default 200). If more rows exist the result has truncated=true and a nextCursor.")),
+		mcp.WithString("cursor", mcp.Description("nextCursor from a previous db.query result; resumes that query (query/database are ignored)")),
+		mcp.WithString("rowFormat", mcp.Enum("arrays", "objects"), mcp.Description("arrays (default): columns metadata plus rows as arrays in column order; objects: rows as name->value maps without column metadata")),
+		withResultFormat(),
+	)
+}
+
+func withResultFormat() mcp.ToolOption {
+	return mcp.WithString("format",
+		mcp.Enum(formatJSON, formatCSV, formatMarkdown, formatNDJSON),
+		mcp.Description("Text rendering of the result: json (default), csv, markdown (compact table) or ndjson. Structured content is always JSON."),
+	)
+}
+
+func withQueryParams() mcp.ToolOption {
+	return mcp.WithArray("params",
+		mcp.Description("Bind parameters. Placeholders may be written as $1.., ? or :name (normalized per driver). "+
+			"Each element is a scalar (string, number, bool, null) or {\"name\"?, \"type\": \"string|number|bool|null|timestamp\", \"value\"}; "+
+			"named elements bind :name placeholders."),
+	)
+}
+
+func toolExportQuery() mcp.Tool {
+	return mcp.NewTool("db.exportQuery",
+		mcp.WithDescription("Stream the full result of a read-only query to a file under the configured export directory (rows do not go through MCP). Returns path, rows, bytes and sha256."),
+		mcp.WithString("connection", mcp.Required(), mcp.Description("Configured connection name")),
+		mcp.WithString("database", mcp.Description("Database name. MySQL: can specify any accessible database; Postgres: uses selected/default if omitted.")),
+		mcp.WithString("query", mcp.Required(), mcp.Description("Query to export (same rules as db.query)")),
+		withQueryParams(),
+		mcp.WithString("format", mcp.Enum(exportCSV, exportNDJSON, exportParquet), mcp.Description("File format (default csv)")),
+		mcp.WithString("path", mcp.Description("File path relative to the export directory (default: a generated export-<time>-<id>.<format>)")),
+		mcp.WithBoolean("overwrite", mcp.Description("Replace an existing file"), mcp.DefaultBool(false)),
 	)
 }
 
@@ -240,16 +449,58 @@ func toolGetDDL() mcp.Tool {
 		mcp.WithDescription("Get table DDL (best effort)."),
 		mcp.WithString("connection", mcp.Required(), mcp.Description("Configured connection name")),
 		mcp.WithString("database", mcp.Description("Database name (MySQL required unless selected/default is set; Postgres uses selected/default if omitted).")),
-		mcp.WithString("schema", mcp.Description("Schema name (Postgres, default public)")),
+		mcp.WithString("schema", mcp.Description("Schema name (Postgres, default public; SQL Server, default dbo; DuckDB, default main)")),
 		mcp.WithString("table", mcp.Required(), mcp.Description("Table name")),
-		mcp.WithBoolean("includeIndexes", mcp.Description("Postgres only; include non-primary index DDLs"), mcp.DefaultBool(false)),
+		mcp.WithBoolean("includeIndexes", mcp.Description("Postgres/SQL Server/SQLite/DuckDB; include non-primary index DDLs (MySQL and ClickHouse always include them)"), mcp.DefaultBool(false)),
 	)
 }
 
 func toolUseDatabase() mcp.Tool {
 	return mcp.NewTool("db.useDatabase",
-		mcp.WithDescription("Select a default database for subsequent operations on this connection (MySQL: like USE; Postgres: switches the underlying connection pool)."),
+		mcp.WithDescription("Select a default database for subsequent operations on this connection in the calling session; other sessions are not affected (MySQL: like USE; Postgres: switches the underlying connection pool)."),
 		mcp.WithString("connection", mcp.Required(), mcp.Description("Configured connection name")),
 		mcp.WithString("database", mcp.Required(), mcp.Description("Database name to select")),
 	)
 }
+
+func toolCurrentContext() mcp.Tool {
+	return mcp.NewTool("db.currentContext",
+		mcp.WithDescription("Report the database and schema tools use when none is given, for the calling session: the database selected with db.useDatabase, else the configured or default one (source tells which)."),
+		mcp.WithString("connection", mcp.Description("Configured connection name. If omitted reports all connections.")),
+		withResultFormat(),
+	)
+}
+
+func toolPoolStats() mcp.Tool {
+	return mcp.NewTool("db.poolStats",
+		mcp.WithDescription("Report the connection pools (one per connection, plus one per extra database on Postgres and SQL Server): open, in-use and idle connections, waits and closed connections, with the usage of the global pool limits."),
+		mcp.WithString("connection", mcp.Description("Configured connection name. If omitted reports all connections.")),
+	)
+}
+
+func toolFind() mcp.Tool {
+	return mcp.NewTool("db.find",
+		mcp.WithDescription("MongoDB: find documents in a collection. Filter, projection and sort are Extended JSON strings or JSON objects; $where and $function are rejected."),
+		mcp.WithString("connection", mcp.Required(), mcp.Description("Configured connection name")),
+		mcp.WithString("database", mcp.Description("Database name. If omitted uses selected/default database.")),
+		mcp.WithString("collection", mcp.Required(), mcp.Description("Collection name")),
+		mcp.WithAny("filter", mcp.Description("Query filter, e.g. {\"status\": \"A\", \"_id\": {\"$oid\": \"...\"}}")),
+		mcp.WithAny("projection", mcp.Description("Projection document")),
+		mcp.WithAny("sort", mcp.Description("Sort document; as a string it keeps key order")),
+		mcp.WithNumber("skip", mcp.Description("Documents to skip")),
+		mcp.WithNumber("limit", mcp.Description("Maximum documents (default 200). If more exist the result has truncated=true.")),
+		withResultFormat(),
+	)
+}
+
+func toolAggregate() mcp.Tool {
+	return mcp.NewTool("db.aggregate",
+		mcp.WithDescription("MongoDB: run a read-only aggregation pipeline. Stages are checked against an allowlist; $out and $merge are rejected."),
+		mcp.WithString("connection", mcp.Required(), mcp.Description("Configured connection name")),
+		mcp.WithString("database", mcp.Description("Database name. If omitted uses selected/default database.")),
+		mcp.WithString("collection", mcp.Required(), mcp.Description("Collection name")),
+		mcp.WithAny("pipeline", mcp.Required(), mcp.Description("Array of stage documents, as an Extended JSON string or JSON array")),
+		mcp.WithNumber("limit", mcp.Description("Maximum documents (default 200). If more exist the result has truncated=true.")),
+		withResultFormat(),
+	)
+}
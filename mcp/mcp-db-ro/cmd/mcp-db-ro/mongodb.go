@@ -0,0 +1,519 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	defaultMongoSampleSize = 100
+	maxMongoSampleSize     = 10000
+	// mongoSchemaMaxDepth bounds how deep describeTable follows embedded
+	// documents and arrays.
+	mongoSchemaMaxDepth = 8
+)
+
+// mongoStore implements docStore. MongoDB has no read-only sessions; only
+// find, aggregate (with an allowlist of stages) and metadata commands are
+// ever sent, and the configured user should only have the read role.
+type mongoStore struct {
+	client *mongo.Client
+}
+
+// findQuery holds the arguments of db.find.
+type findQuery struct {
+	Filter     bson.D
+	Projection bson.D
+	Sort       bson.D
+	Skip       int64
+	Limit      int
+}
+
+func init() {
+	registerDriver(driverSpec{
+		kind:          DriverMongoDB,
+		aliases:       []string{"mongo"},
+		newDocStore:   newMongoStore,
+		dsn:           mongoURI,
+		checkDatabase: mongoCheckDatabase,
+	})
+}
+
+func newMongoStore(cfg ConnectionConfig) (docStore, error) {
+	uri, err := mongoURI(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client, err := mongo.Connect(options.Client().
+		ApplyURI(uri).
+		SetAppName("mcp-db-ro").
+		SetConnectTimeout(5 * time.Second).
+		SetMaxPoolSize(uint64(cfg.Pool.maxOpen())))
+	if err != nil {
+		return nil, err
+	}
+	return &mongoStore{client: client}, nil
+}
+
+// mongoCheckDatabase accepts any name: users without listDatabases only see
+// databases they hold roles on, so an unlisted name is selected with a note.
+func mongoCheckDatabase(ctx context.Context, c *dbClient, database string) (string, error) {
+	dbs, err := c.docs.ListDatabases(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, d := range dbs {
+		if d["name"] == database {
+			return "", nil
+		}
+	}
+	return "database selected but not listed (it may not exist or may not be readable)", nil
+}
+
+// mongoURI builds a mongodb:// URI. Database becomes the default
+// authentication database; params are URI options (authSource, replicaSet,
+// tls, readPreference, ...).
+func mongoURI(cfg ConnectionConfig) (string, error) {
+	host := strings.TrimSpace(cfg.Host)
+	if host == "" {
+		return "", fmt.Errorf("host is required")
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = 27017
+	}
+	q := url.Values{}
+	for _, kv := range sortedKV(cfg.Params) {
+		q.Set(kv[0], kv[1])
+	}
+	u := url.URL{
+		Scheme:   "mongodb",
+		Host:     net.JoinHostPort(host, strconv.Itoa(port)),
+		Path:     "/" + strings.TrimSpace(cfg.Database),
+		RawQuery: q.Encode(),
+	}
+	if username := strings.TrimSpace(cfg.Username); username != "" {
+		u.User = url.UserPassword(username, cfg.Password)
+	}
+	return u.String(), nil
+}
+
+func (*mongoStore) Kind() DriverKind { return DriverMongoDB }
+
+func (s *mongoStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx, nil)
+}
+
+func (s *mongoStore) Close(ctx context.Context) error {
+	return s.client.Disconnect(ctx)
+}
+
+// ListDatabases only lists the databases the user can read.
+func (s *mongoStore) ListDatabases(ctx context.Context) ([]map[string]any, error) {
+	res, err := s.client.ListDatabases(ctx, bson.D{}, options.ListDatabases().SetAuthorizedDatabases(true))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]map[string]any, 0, len(res.Databases))
+	for _, d := range res.Databases {
+		out = append(out, map[string]any{
+			"name":       d.Name,
+			"sizeOnDisk": d.SizeOnDisk,
+			"empty":      d.Empty,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i]["name"].(string) < out[j]["name"].(string) })
+	return out, nil
+}
+
+func (s *mongoStore) ListCollections(ctx context.Context, database string) ([]map[string]any, error) {
+	cur, err := s.client.Database(database).ListCollections(ctx, bson.D{}, options.ListCollections().SetAuthorizedCollections(true))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out []map[string]any
+	for cur.Next(ctx) {
+		// Malformed entries are skipped rather than panicking the server.
+		name, ok := cur.Current.Lookup("name").StringValueOK()
+		if !ok {
+			continue
+		}
+		row := map[string]any{"name": name}
+		if typ, ok := cur.Current.Lookup("type").StringValueOK(); ok {
+			row["type"] = typ
+		}
+		if opts, ok := cur.Current.Lookup("options").DocumentOK(); ok && len(mustElements(opts)) > 0 {
+			v, err := mongoJSONValue(cur.Current.Lookup("options"))
+			if err != nil {
+				return nil, err
+			}
+			row["options"] = v
+		}
+		out = append(out, row)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i]["name"].(string) < out[j]["name"].(string) })
+	return out, nil
+}
+
+// DescribeCollection infers a schema from a random sample of documents: one
+// row per field path with the BSON types seen and how often the field is
+// present. Array elements appear as path[].
+func (s *mongoStore) DescribeCollection(ctx context.Context, database, collection string, sampleSize int) ([]map[string]any, error) {
+	if sampleSize <= 0 {
+		sampleSize = defaultMongoSampleSize
+	}
+	if sampleSize > maxMongoSampleSize {
+		return nil, fmt.Errorf("sampleSize must be at most %d", maxMongoSampleSize)
+	}
+	cur, err := s.client.Database(database).Collection(collection).Aggregate(ctx,
+		bson.A{bson.D{{Key: "$sample", Value: bson.D{{Key: "size", Value: sampleSize}}}}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	schema := newMongoSchema()
+	for cur.Next(ctx) {
+		schema.add(cur.Current)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return schema.rows(), nil
+}
+
+func (s *mongoStore) ListIndexes(ctx context.Context, database, collection string) ([]map[string]any, error) {
+	cur, err := s.client.Database(database).Collection(collection).Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out []map[string]any
+	for cur.Next(ctx) {
+		row, _, err := mongoRow(cur.Current)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *mongoStore) Find(ctx context.Context, database, collection string, q findQuery) (queryResult, error) {
+	opts := options.Find().SetLimit(int64(q.Limit) + 1)
+	if q.Skip > 0 {
+		opts.SetSkip(q.Skip)
+	}
+	if q.Projection != nil {
+		opts.SetProjection(q.Projection)
+	}
+	if q.Sort != nil {
+		opts.SetSort(q.Sort)
+	}
+	filter := q.Filter
+	if filter == nil {
+		filter = bson.D{}
+	}
+	cur, err := s.client.Database(database).Collection(collection).Find(ctx, filter, opts)
+	if err != nil {
+		return queryResult{}, err
+	}
+	return readMongoDocs(ctx, cur, q.Limit)
+}
+
+// Aggregate runs pipeline with a trailing $limit, so at most one document
+// more than limit is transferred.
+func (s *mongoStore) Aggregate(ctx context.Context, database, collection string, pipeline []bson.D, limit int) (queryResult, error) {
+	stages := make(bson.A, 0, len(pipeline)+1)
+	for _, st := range pipeline {
+		stages = append(stages, st)
+	}
+	stages = append(stages, bson.D{{Key: "$limit", Value: limit + 1}})
+	cur, err := s.client.Database(database).Collection(collection).Aggregate(ctx, stages)
+	if err != nil {
+		return queryResult{}, err
+	}
+	return readMongoDocs(ctx, cur, limit)
+}
+
+// Explain runs the explain command for a find, aggregate, count or distinct
+// command document.
+func (s *mongoStore) Explain(ctx context.Context, database string, command bson.D, verbosity string) (map[string]any, error) {
+	switch verbosity {
+	case "":
+		verbosity = "queryPlanner"
+	case "queryPlanner", "executionStats", "allPlansExecution":
+	default:
+		return nil, fmt.Errorf("unsupported format: %s (mongodb supports: queryPlanner, executionStats, allPlansExecution)", verbosity)
+	}
+	raw, err := s.client.Database(database).RunCommand(ctx, bson.D{
+		{Key: "explain", Value: command},
+		{Key: "verbosity", Value: verbosity},
+	}).Raw()
+	if err != nil {
+		return nil, err
+	}
+	row, _, err := mongoRow(raw)
+	return row, err
+}
+
+// readMongoDocs returns up to limit documents of cur as object rows; columns
+// are the top-level fields in order of first appearance.
+func readMongoDocs(ctx context.Context, cur *mongo.Cursor, limit int) (queryResult, error) {
+	defer cur.Close(ctx)
+
+	rows := []map[string]any{}
+	var columns []resultColumn
+	seen := map[string]bool{}
+	more := false
+	for cur.Next(ctx) {
+		if len(rows) == limit {
+			more = true
+			break
+		}
+		row, keys, err := mongoRow(cur.Current)
+		if err != nil {
+			return queryResult{}, err
+		}
+		for _, k := range keys {
+			if !seen[k] {
+				seen[k] = true
+				columns = append(columns, resultColumn{Name: k})
+			}
+		}
+		rows = append(rows, row)
+	}
+	if err := cur.Err(); err != nil {
+		return queryResult{}, err
+	}
+	return queryResult{Rows: rows, columns: columns, Truncated: more, RowsRead: len(rows)}, nil
+}
+
+// mongoRow converts a document into a row keyed by its top-level fields and
+// returns the keys in document order. Values use relaxed Extended JSON, so
+// ObjectIds, dates and decimals keep their type ({"$oid": ...}, ...).
+func mongoRow(doc bson.Raw) (map[string]any, []string, error) {
+	elems, err := doc.Elements()
+	if err != nil {
+		return nil, nil, err
+	}
+	row := make(map[string]any, len(elems))
+	keys := make([]string, 0, len(elems))
+	for _, el := range elems {
+		v, err := mongoJSONValue(el.Value())
+		if err != nil {
+			return nil, nil, err
+		}
+		row[el.Key()] = v
+		keys = append(keys, el.Key())
+	}
+	return row, keys, nil
+}
+
+// mongoJSONValue returns strings as is and any other value as relaxed
+// Extended JSON.
+func mongoJSONValue(v bson.RawValue) (any, error) {
+	if s, ok := v.StringValueOK(); ok {
+		return s, nil
+	}
+	if v.Type == bson.TypeNull {
+		return nil, nil
+	}
+	b, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: v}}, false, false)
+	if err != nil {
+		return nil, err
+	}
+	var wrapped struct {
+		V json.RawMessage `json:"v"`
+	}
+	if err := json.Unmarshal(b, &wrapped); err != nil {
+		return nil, err
+	}
+	return wrapped.V, nil
+}
+
+func mustElements(doc bson.Raw) []bson.RawElement {
+	elems, _ := doc.Elements()
+	return elems
+}
+
+// mongoTypeNames are the $type aliases of the BSON types.
+var mongoTypeNames = map[bson.Type]string{
+	bson.TypeDouble:           "double",
+	bson.TypeString:           "string",
+	bson.TypeEmbeddedDocument: "object",
+	bson.TypeArray:            "array",
+	bson.TypeBinary:           "binData",
+	bson.TypeUndefined:        "undefined",
+	bson.TypeObjectID:         "objectId",
+	bson.TypeBoolean:          "bool",
+	bson.TypeDateTime:         "date",
+	bson.TypeNull:             "null",
+	bson.TypeRegex:            "regex",
+	bson.TypeDBPointer:        "dbPointer",
+	bson.TypeJavaScript:       "javascript",
+	bson.TypeSymbol:           "symbol",
+	bson.TypeCodeWithScope:    "javascriptWithScope",
+	bson.TypeInt32:            "int",
+	bson.TypeTimestamp:        "timestamp",
+	bson.TypeInt64:            "long",
+	bson.TypeDecimal128:       "decimal",
+	bson.TypeMinKey:           "minKey",
+	bson.TypeMaxKey:           "maxKey",
+}
+
+func mongoTypeName(t bson.Type) string {
+	if name, ok := mongoTypeNames[t]; ok {
+		return name
+	}
+	return t.String()
+}
+
+// mongoSchema accumulates, per field path, in how many sampled documents the
+// field occurs and with which types.
+type mongoSchema struct {
+	docs   int
+	fields map[string]*mongoField
+}
+
+type mongoField struct {
+	count int
+	types map[string]int
+}
+
+func newMongoSchema() *mongoSchema {
+	return &mongoSchema{fields: map[string]*mongoField{}}
+}
+
+func (s *mongoSchema) add(doc bson.Raw) {
+	s.docs++
+	// Each path and type is counted once per document.
+	seen := map[string]map[string]bool{}
+	mongoWalk("", doc, 0, func(path, typ string) {
+		if seen[path] == nil {
+			seen[path] = map[string]bool{}
+		}
+		seen[path][typ] = true
+	})
+	for path, types := range seen {
+		f := s.fields[path]
+		if f == nil {
+			f = &mongoField{types: map[string]int{}}
+			s.fields[path] = f
+		}
+		f.count++
+		for t := range types {
+			f.types[t]++
+		}
+	}
+}
+
+func mongoWalk(prefix string, doc bson.Raw, depth int, visit func(path, typ string)) {
+	for _, el := range mustElements(doc) {
+		path := prefix + el.Key()
+		mongoWalkValue(path, el.Value(), depth, visit)
+	}
+}
+
+func mongoWalkValue(path string, v bson.RawValue, depth int, visit func(path, typ string)) {
+	visit(path, mongoTypeName(v.Type))
+	if depth >= mongoSchemaMaxDepth {
+		return
+	}
+	switch v.Type {
+	case bson.TypeEmbeddedDocument:
+		mongoWalk(path+".", v.Document(), depth+1, visit)
+	case bson.TypeArray:
+		values, _ := v.Array().Values()
+		for _, e := range values {
+			mongoWalkValue(path+"[]", e, depth+1, visit)
+		}
+	}
+}
+
+func (s *mongoSchema) rows() []map[string]any {
+	paths := make([]string, 0, len(s.fields))
+	for p := range s.fields {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	out := make([]map[string]any, 0, len(paths))
+	for _, p := range paths {
+		f := s.fields[p]
+		out = append(out, map[string]any{
+			"field":     p,
+			"types":     f.types,
+			"count":     f.count,
+			"frequency": float64(f.count) / float64(s.docs),
+		})
+	}
+	return out
+}
+
+// parseMongoDoc reads a filter, projection or sort given either as an
+// Extended JSON string (which keeps key order and allows {"$oid": ...},
+// {"$date": ...}) or as a JSON object.
+func parseMongoDoc(name string, v any) (bson.D, error) {
+	b, err := mongoArgJSON(v)
+	if err != nil || b == nil {
+		return nil, err
+	}
+	var doc bson.D
+	if err := bson.UnmarshalExtJSON(b, false, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return doc, nil
+}
+
+// parseMongoPipeline reads an aggregation pipeline given as an Extended JSON
+// string or a JSON array of stage objects.
+func parseMongoPipeline(v any) ([]bson.D, error) {
+	b, err := mongoArgJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, fmt.Errorf("pipeline is required")
+	}
+	var wrapped struct {
+		Pipeline []bson.D `bson:"pipeline"`
+	}
+	if err := bson.UnmarshalExtJSON([]byte(`{"pipeline":`+string(b)+`}`), false, &wrapped); err != nil {
+		return nil, fmt.Errorf("pipeline: %w", err)
+	}
+	return wrapped.Pipeline, nil
+}
+
+func mongoArgJSON(v any) ([]byte, error) {
+	switch x := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if strings.TrimSpace(x) == "" {
+			return nil, nil
+		}
+		return []byte(x), nil
+	default:
+		return json.Marshal(x)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Block codes specific to MongoDB requests (see sqlBlockError).
+const (
+	blockPipelineStage = "pipeline_stage_not_allowed"
+	blockServerScript  = "server_side_javascript"
+)
+
+// mongoAllowedStages are the aggregation stages db.aggregate accepts. They
+// only read the collection (and, for $lookup/$graphLookup/$unionWith, other
+// collections of the same database). $out and $merge write, and stages such
+// as $currentOp or $collStats expose server state, so they are refused.
+var mongoAllowedStages = map[string]bool{
+	"$match":           true,
+	"$project":         true,
+	"$addFields":       true,
+	"$set":             true,
+	"$unset":           true,
+	"$group":           true,
+	"$sort":            true,
+	"$limit":           true,
+	"$skip":            true,
+	"$unwind":          true,
+	"$lookup":          true,
+	"$graphLookup":     true,
+	"$unionWith":       true,
+	"$facet":           true,
+	"$bucket":          true,
+	"$bucketAuto":      true,
+	"$count":           true,
+	"$sortByCount":     true,
+	"$replaceRoot":     true,
+	"$replaceWith":     true,
+	"$sample":          true,
+	"$geoNear":         true,
+	"$redact":          true,
+	"$densify":         true,
+	"$fill":            true,
+	"$setWindowFields": true,
+	"$documents":       true,
+}
+
+// mongoScriptOperators run JavaScript on the server.
+var mongoScriptOperators = map[string]bool{
+	"$where":       true,
+	"$function":    true,
+	"$accumulator": true,
+}
+
+// mongoExplainable are the commands db.explain accepts on MongoDB.
+var mongoExplainable = map[string]bool{
+	"find":      true,
+	"aggregate": true,
+	"count":     true,
+	"distinct":  true,
+}
+
+func mongoBlock(code, reason, token string) *sqlBlockError {
+	return &sqlBlockError{Code: code, Reason: reason, Token: token, Dialect: "mongodb"}
+}
+
+// checkMongoPipeline rejects stages outside the allowlist, including inside
+// $facet, $lookup and $unionWith sub-pipelines, and server-side JavaScript.
+func checkMongoPipeline(pipeline []bson.D) error {
+	for i, stage := range pipeline {
+		if len(stage) != 1 {
+			return mongoBlock(blockPipelineStage, fmt.Sprintf("pipeline stage %d must have exactly one field", i), "")
+		}
+		name := stage[0].Key
+		switch {
+		case name == "$out" || name == "$merge":
+			return mongoBlock(blockDataModifying, fmt.Sprintf("%s writes the result to a collection", name), name)
+		case !mongoAllowedStages[name]:
+			return mongoBlock(blockPipelineStage, fmt.Sprintf("stage %s is not allowed", name), name)
+		}
+		for _, sub := range mongoSubPipelines(name, stage[0].Value) {
+			if err := checkMongoPipeline(sub); err != nil {
+				return err
+			}
+		}
+		if err := checkMongoScripts(stage[0].Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mongoSubPipelines returns the pipelines nested in a $facet, $lookup or
+// $unionWith stage.
+func mongoSubPipelines(stage string, spec any) [][]bson.D {
+	doc, ok := spec.(bson.D)
+	if !ok {
+		return nil
+	}
+	var out [][]bson.D
+	for _, e := range doc {
+		if stage == "$facet" || e.Key == "pipeline" {
+			out = append(out, mongoStages(e.Value))
+		}
+	}
+	return out
+}
+
+func mongoStages(v any) []bson.D {
+	arr, _ := v.(bson.A)
+	out := make([]bson.D, 0, len(arr))
+	for _, s := range arr {
+		doc, ok := s.(bson.D)
+		if !ok {
+			// Not a document; keep a placeholder the check rejects.
+			doc = bson.D{}
+		}
+		out = append(out, doc)
+	}
+	return out
+}
+
+// checkMongoScripts rejects $where, $function and $accumulator anywhere in v.
+func checkMongoScripts(v any) error {
+	switch x := v.(type) {
+	case bson.D:
+		for _, e := range x {
+			if mongoScriptOperators[e.Key] {
+				return mongoBlock(blockServerScript, fmt.Sprintf("%s runs JavaScript on the server and is not allowed", e.Key), e.Key)
+			}
+			if err := checkMongoScripts(e.Value); err != nil {
+				return err
+			}
+		}
+	case bson.A:
+		for _, e := range x {
+			if err := checkMongoScripts(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkMongoExplain accepts a find, aggregate, count or distinct command
+// that passes the same checks as db.find and db.aggregate.
+func checkMongoExplain(cmd bson.D) error {
+	if len(cmd) == 0 || !mongoExplainable[cmd[0].Key] {
+		token := ""
+		if len(cmd) > 0 {
+			token = cmd[0].Key
+		}
+		return mongoBlock(blockExplainTarget, "only find, aggregate, count and distinct commands can be explained", token)
+	}
+	if cmd[0].Key == "aggregate" {
+		for _, e := range cmd {
+			if e.Key == "pipeline" {
+				if err := checkMongoPipeline(mongoStages(e.Value)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return checkMongoScripts(cmd)
+}
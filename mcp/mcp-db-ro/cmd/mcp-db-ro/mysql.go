@@ -3,13 +3,72 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"strings"
+	"time"
+
+	mysqlcfg "github.com/go-sql-driver/mysql"
 )
 
 type mysqlDriver struct{}
 
+func init() {
+	registerDriver(driverSpec{
+		kind:          DriverMySQL,
+		sqlDriverName: "mysql",
+		openWith:      mysqlOpenWith,
+		newDriver:     func() DBDriver { return mysqlDriver{} },
+		dsn:           mysqlDSN,
+		credentials:   mysqlCredentials,
+		urlSchemes:    []string{"mysql"},
+		parseURL:      mysqlURL,
+		// Table tools need a database; db.listTables can list across all.
+		scope:         scopeRules{useDefaultDatabase: true, requireDatabase: true},
+		checkDatabase: mysqlCheckDatabase,
+		dialect:       mysqlDialect,
+		placeholders:  placeholderQuestion,
+	})
+}
+
 func (mysqlDriver) Kind() DriverKind { return DriverMySQL }
 
+// mysqlTLSName is the name mysqlOpenWith registers a connection's TLS
+// settings under.
+func mysqlTLSName(connection string) string { return "mcp-db-ro/" + connection }
+
+// mysqlOpenWith opens a go-sql-driver/mysql pool through dial and with the
+// connection's TLS settings registered for the DSN to use.
+func mysqlOpenWith(cfg ConnectionConfig, dsn string, dial dialFunc, tls *tlsState) (*sql.DB, error) {
+	if tls != nil {
+		if err := mysqlcfg.RegisterTLSConfig(mysqlTLSName(cfg.Name), tls.clientConfig()); err != nil {
+			return nil, err
+		}
+	}
+	c, err := mysqlcfg.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if dial != nil {
+		c.DialFunc = dial
+	}
+	connector, err := mysqlcfg.NewConnector(c)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(connector), nil
+}
+
+// mysqlCheckDatabase checks that the database is visible in
+// information_schema. If that cannot be queried (permissions), the database
+// is selected anyway.
+func mysqlCheckDatabase(ctx context.Context, c *dbClient, database string) (string, error) {
+	if _, err := queryAll(ctx, c.db, `SELECT schema_name FROM information_schema.schemata WHERE schema_name = ? LIMIT 1`, database); err != nil {
+		return "database selected; validation skipped due to error: " + err.Error(), nil
+	}
+	return "", nil
+}
+
 func (mysqlDriver) ListDatabases(ctx context.Context, db *sql.DB) ([]map[string]any, error) {
 	return queryAll(ctx, db, `SHOW DATABASES`)
 }
@@ -89,6 +148,52 @@ WHERE table_schema = ? AND table_name = ?
 ORDER BY partition_ordinal_position, subpartition_ordinal_position`, ref.Database, ref.Table)
 }
 
-func (mysqlDriver) Explain(ctx context.Context, db *sql.DB, query, _ string) ([]map[string]any, error) {
-	return queryAll(ctx, db, "EXPLAIN "+query)
+func (mysqlDriver) Explain(ctx context.Context, db queryer, query, format string, args ...any) ([]map[string]any, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "text", "traditional":
+		return queryAll(ctx, db, "EXPLAIN "+query, args...)
+	case "json":
+		return queryAll(ctx, db, "EXPLAIN FORMAT=JSON "+query, args...)
+	case "tree":
+		return queryAll(ctx, db, "EXPLAIN FORMAT=TREE "+query, args...)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s (mysql supports: text, json, tree)", format)
+	}
+}
+
+func (mysqlDriver) SessionID(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var id int64
+	err := conn.QueryRowContext(ctx, `SELECT CONNECTION_ID()`).Scan(&id)
+	return id, err
+}
+
+// SetStatementTimeout uses max_execution_time, which MySQL applies to SELECT
+// statements only; the session variable is set again every time the connection
+// is pinned for a query.
+func (mysqlDriver) SetStatementTimeout(ctx context.Context, q queryer, timeout time.Duration, _ bool) error {
+	_, err := q.ExecContext(ctx, fmt.Sprintf("SET SESSION max_execution_time = %d", timeout.Milliseconds()))
+	return err
+}
+
+func (mysqlDriver) CancelSession(ctx context.Context, db *sql.DB, id int64) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf("KILL QUERY %d", id))
+	return err
+}
+
+// PageQuery wraps a query in a derived table so later pages only transfer the
+// requested rows. Row order is only stable if the query has an ORDER BY.
+func (mysqlDriver) PageQuery(query string, limit, offset int) (string, bool) {
+	switch leadingKeyword(DriverMySQL, query) {
+	case "SELECT", "WITH", "VALUES", "TABLE":
+		return fmt.Sprintf("SELECT * FROM (\n%s\n) AS mcp_page LIMIT %d OFFSET %d", query, limit, offset), true
+	default:
+		return "", false
+	}
+}
+
+// IsPageRewriteError matches ER_DUP_FIELDNAME, raised when the wrapped query
+// selects the same column name twice.
+func (mysqlDriver) IsPageRewriteError(err error) bool {
+	var me *mysqlcfg.MySQLError
+	return errors.As(err, &me) && me.Number == 1060
 }
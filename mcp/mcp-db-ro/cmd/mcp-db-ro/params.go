@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// queryParam is one bound value of db.query/db.explain. Name is set for
+// :name placeholders.
+type queryParam struct {
+	Name  string
+	Value any
+}
+
+// parseQueryParams converts the tool's params argument. Each element is
+// either a plain JSON scalar (type inferred) or an object
+// {"name"?, "type"?, "value"} where type is string|number|bool|null|timestamp.
+func parseQueryParams(raw any) ([]queryParam, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	list, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("params must be an array")
+	}
+	out := make([]queryParam, 0, len(list))
+	for i, el := range list {
+		var p queryParam
+		typ := ""
+		value := el
+		if obj, ok := el.(map[string]any); ok {
+			value = obj["value"]
+			if v, ok := obj["name"].(string); ok {
+				p.Name = strings.TrimPrefix(strings.TrimSpace(v), ":")
+			}
+			if v, ok := obj["type"].(string); ok {
+				typ = strings.ToLower(strings.TrimSpace(v))
+			}
+		}
+		v, err := convertParam(typ, value)
+		if err != nil {
+			return nil, fmt.Errorf("params[%d]: %w", i, err)
+		}
+		p.Value = v
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func convertParam(typ string, v any) (any, error) {
+	if typ == "" {
+		switch v.(type) {
+		case nil:
+			typ = "null"
+		case bool:
+			typ = "bool"
+		case float64:
+			typ = "number"
+		case string:
+			typ = "string"
+		default:
+			return nil, fmt.Errorf("unsupported value %T (use a scalar or {type, value})", v)
+		}
+	}
+	switch typ {
+	case "null":
+		if v != nil {
+			return nil, fmt.Errorf("null parameter must have a null value")
+		}
+		return nil, nil
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", v)
+		}
+		return s, nil
+	case "bool", "boolean":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected bool, got %T", v)
+		}
+		return b, nil
+	case "number":
+		switch x := v.(type) {
+		case float64:
+			if x == math.Trunc(x) && math.Abs(x) < 1<<53 {
+				return int64(x), nil
+			}
+			return x, nil
+		case string:
+			// Kept as text so exact decimals survive; the server casts it.
+			if _, err := strconv.ParseFloat(strings.TrimSpace(x), 64); err != nil {
+				return nil, fmt.Errorf("invalid number %q", x)
+			}
+			return strings.TrimSpace(x), nil
+		default:
+			return nil, fmt.Errorf("expected number, got %T", v)
+		}
+	case "timestamp":
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected RFC 3339 timestamp string, got %T", v)
+		}
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid timestamp %q (use RFC 3339)", s)
+	default:
+		return nil, fmt.Errorf("unsupported type %q (supported: string, number, bool, null, timestamp)", typ)
+	}
+}
+
+func (c *dbClient) bindParams(q string, params []queryParam) (string, []any, error) {
+	if len(params) == 0 {
+		return q, nil, nil
+	}
+	return bindParams(c.driver.Kind(), q, params)
+}
+
+// bindParams rewrites the placeholders in q into the driver's native style
+// ($1.. for Postgres and ClickHouse, ? for MySQL, ?1.. for SQLite, @p1.. for
+// SQL Server) and returns the matching argument list.
+// Placeholders may be written as $n, ? or :name, but styles cannot be mixed.
+// On Postgres ? is only treated as a placeholder when no other style is used,
+// since it is also the jsonb key-exists operator.
+func bindParams(kind DriverKind, q string, params []queryParam) (string, []any, error) {
+	dialect := sqlDialectFor(kind)
+	tokens, err := lexSQL(dialect, q)
+	if err != nil {
+		return "", nil, err
+	}
+
+	named := map[string]int{}
+	positional := 0
+	for i, p := range params {
+		if p.Name == "" {
+			positional++
+			continue
+		}
+		if _, dup := named[p.Name]; dup {
+			return "", nil, fmt.Errorf("duplicate parameter name: %s", p.Name)
+		}
+		named[p.Name] = i
+	}
+	if positional > 0 && len(named) > 0 {
+		return "", nil, fmt.Errorf("params cannot mix named and positional values")
+	}
+
+	// numbered holds $n (and SQLite ?n) placeholders.
+	var numbered, question, colon []sqlToken
+	for _, t := range tokens {
+		if t.kind != tokParam {
+			continue
+		}
+		switch {
+		case t.text == "?":
+			question = append(question, t)
+		case strings.HasPrefix(t.text, "$"), strings.HasPrefix(t.text, "?"):
+			numbered = append(numbered, t)
+		case strings.HasPrefix(t.text, ":"):
+			if _, ok := named[t.text[1:]]; ok {
+				colon = append(colon, t)
+			}
+		}
+	}
+	if dialect.questionOperator && (len(numbered) > 0 || len(colon) > 0) {
+		question = nil
+	}
+	styles := 0
+	for _, s := range [][]sqlToken{numbered, question, colon} {
+		if len(s) > 0 {
+			styles++
+		}
+	}
+	if styles > 1 {
+		return "", nil, fmt.Errorf("query mixes placeholder styles ($n, ?, :name)")
+	}
+	if len(colon) == 0 && len(named) > 0 {
+		return "", nil, fmt.Errorf("named params given but the query has no :name placeholders")
+	}
+
+	// For each placeholder occurrence, pick the params index it refers to.
+	var occurrences []sqlToken
+	var refs []int
+	switch {
+	case len(numbered) > 0:
+		occurrences = numbered
+		for _, t := range numbered {
+			n, _ := strconv.Atoi(t.text[1:])
+			if n < 1 || n > len(params) {
+				return "", nil, fmt.Errorf("placeholder %s has no matching param (%d given)", t.text, len(params))
+			}
+			refs = append(refs, n-1)
+		}
+	case len(question) > 0:
+		occurrences = question
+		if len(question) != len(params) {
+			return "", nil, fmt.Errorf("query has %d ? placeholders but %d params were given", len(question), len(params))
+		}
+		for i := range question {
+			refs = append(refs, i)
+		}
+	case len(colon) > 0:
+		occurrences = colon
+		for _, t := range colon {
+			refs = append(refs, named[t.text[1:]])
+		}
+	}
+
+	used := make([]bool, len(params))
+	for _, r := range refs {
+		used[r] = true
+	}
+	for i, u := range used {
+		if !u {
+			if params[i].Name != "" {
+				return "", nil, fmt.Errorf("param %s is not referenced by the query", params[i].Name)
+			}
+			return "", nil, fmt.Errorf("params[%d] is not referenced by the query", i)
+		}
+	}
+
+	// Rewrite placeholders into the driver's native style.
+	var b strings.Builder
+	var args []any
+	style := placeholdersFor(kind)
+	native := map[int]int{} // params index -> $n / ?n / @pn
+	last := 0
+	for i, t := range occurrences {
+		b.WriteString(q[last:t.pos])
+		last = t.pos + len(t.text)
+		ref := refs[i]
+		if style == placeholderQuestion {
+			b.WriteString("?")
+			args = append(args, params[ref].Value)
+			continue
+		}
+		n, ok := native[ref]
+		if !ok {
+			args = append(args, params[ref].Value)
+			n = len(args)
+			native[ref] = n
+		}
+		switch style {
+		case placeholderNumberedQuestion:
+			b.WriteString("?" + strconv.Itoa(n))
+		case placeholderAtP:
+			b.WriteString("@p" + strconv.Itoa(n))
+		default:
+			b.WriteString("$" + strconv.Itoa(n))
+		}
+	}
+	b.WriteString(q[last:])
+	return b.String(), args, nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConvertParam(t *testing.T) {
+	for _, tc := range []struct {
+		typ     string
+		value   any
+		want    any
+		wantErr string
+	}{
+		{"", nil, nil, ""},
+		{"", true, true, ""},
+		{"", float64(42), int64(42), ""},
+		{"", float64(-3), int64(-3), ""},
+		{"", 1.5, 1.5, ""},
+		{"", float64(1 << 60), float64(1 << 60), ""},
+		{"", "x", "x", ""},
+		{"", []any{1}, nil, "unsupported value"},
+		{"", map[string]any{}, nil, "unsupported value"},
+		{"null", nil, nil, ""},
+		{"null", "x", nil, "null value"},
+		{"string", "42", "42", ""},
+		{"string", float64(42), nil, "expected string"},
+		{"bool", false, false, ""},
+		{"boolean", true, true, ""},
+		{"bool", "true", nil, "expected bool"},
+		{"number", float64(7), int64(7), ""},
+		{"number", " 12345678901234567890.125 ", "12345678901234567890.125", ""},
+		{"number", "abc", nil, "invalid number"},
+		{"number", true, nil, "expected number"},
+		{"timestamp", "2024-05-01T10:20:30Z", time.Date(2024, 5, 1, 10, 20, 30, 0, time.UTC), ""},
+		{"timestamp", "2024-05-01T10:20:30.5+02:00", time.Date(2024, 5, 1, 8, 20, 30, 5e8, time.UTC), ""},
+		{"timestamp", "2024-05-01 10:20:30", time.Date(2024, 5, 1, 10, 20, 30, 0, time.UTC), ""},
+		{"timestamp", "2024-05-01", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), ""},
+		{"timestamp", "yesterday", nil, "invalid timestamp"},
+		{"timestamp", float64(0), nil, "expected RFC 3339"},
+		{"uuid", "x", nil, "unsupported type"},
+	} {
+		got, err := convertParam(tc.typ, tc.value)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("convertParam(%q, %#v): err %v, want %q", tc.typ, tc.value, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("convertParam(%q, %#v): %v", tc.typ, tc.value, err)
+			continue
+		}
+		if gt, ok := got.(time.Time); ok {
+			if want := tc.want.(time.Time); !gt.Equal(want) {
+				t.Errorf("convertParam(%q, %#v) = %v, want %v", tc.typ, tc.value, gt, want)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("convertParam(%q, %#v) = %#v, want %#v", tc.typ, tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestParseQueryParams(t *testing.T) {
+	got, err := parseQueryParams([]any{
+		"a",
+		float64(1),
+		map[string]any{"name": " :id ", "type": "NUMBER", "value": "10.50"},
+		map[string]any{"value": nil},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []queryParam{{Value: "a"}, {Value: int64(1)}, {Name: "id", Value: "10.50"}, {Value: nil}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseQueryParams = %#v, want %#v", got, want)
+	}
+
+	if _, err := parseQueryParams(map[string]any{}); err == nil {
+		t.Error("params given as an object: want error")
+	}
+	if _, err := parseQueryParams([]any{"ok", map[string]any{"type": "bool", "value": "no"}}); err == nil || !strings.Contains(err.Error(), "params[1]") {
+		t.Errorf("bad element: err %v, want it to name params[1]", err)
+	}
+	if got, err := parseQueryParams(nil); got != nil || err != nil {
+		t.Errorf("parseQueryParams(nil) = %v, %v", got, err)
+	}
+}
+
+func positionalParams(values ...any) []queryParam {
+	out := make([]queryParam, len(values))
+	for i, v := range values {
+		out[i] = queryParam{Value: v}
+	}
+	return out
+}
+
+func TestBindParams(t *testing.T) {
+	named := func(kv ...any) []queryParam {
+		var out []queryParam
+		for i := 0; i < len(kv); i += 2 {
+			out = append(out, queryParam{Name: kv[i].(string), Value: kv[i+1]})
+		}
+		return out
+	}
+	for _, tc := range []struct {
+		name    string
+		kind    DriverKind
+		query   string
+		params  []queryParam
+		want    string
+		args    []any
+		wantErr string
+	}{
+		{"pg dollar", DriverPostgres, `SELECT * FROM t WHERE a = $1 AND b = $2`, positionalParams(1, "x"),
+			`SELECT * FROM t WHERE a = $1 AND b = $2`, []any{1, "x"}, ""},
+		{"pg repeated dollar", DriverPostgres, `SELECT $1 + $1`, positionalParams(2),
+			`SELECT $1 + $1`, []any{2}, ""},
+		{"pg dollar out of order", DriverPostgres, `SELECT $2, $1`, positionalParams("a", "b"),
+			`SELECT $1, $2`, []any{"b", "a"}, ""},
+		{"pg question", DriverPostgres, `SELECT * FROM t WHERE a = ? AND b = ?`, positionalParams(1, 2),
+			`SELECT * FROM t WHERE a = $1 AND b = $2`, []any{1, 2}, ""},
+		{"pg jsonb operator kept with dollar", DriverPostgres, `SELECT * FROM t WHERE data ? 'k' AND id = $1`, positionalParams(3),
+			`SELECT * FROM t WHERE data ? 'k' AND id = $1`, []any{3}, ""},
+		{"pg jsonb operator kept with name", DriverPostgres, `SELECT * FROM t WHERE data ?| array['a'] AND id = :id`, named("id", 3),
+			`SELECT * FROM t WHERE data ?| array['a'] AND id = $1`, []any{3}, ""},
+		{"pg name reused", DriverPostgres, `SELECT * FROM t WHERE a = :v OR b = :v`, named("v", 5),
+			`SELECT * FROM t WHERE a = $1 OR b = $1`, []any{5}, ""},
+		{"pg names in order of use", DriverPostgres, `SELECT :b, :a, :b`, named("a", 1, "b", 2),
+			`SELECT $1, $2, $1`, []any{2, 1}, ""},
+		{"pg cast and literals untouched", DriverPostgres, `SELECT now()::date, '$1 ? :x', $1`, positionalParams(1),
+			`SELECT now()::date, '$1 ? :x', $1`, []any{1}, ""},
+		{"pg dollar quote untouched", DriverPostgres, `SELECT $$ :a $1 $$, :a`, named("a", 1),
+			`SELECT $$ :a $1 $$, $1`, []any{1}, ""},
+		{"mysql question", DriverMySQL, `SELECT * FROM t WHERE a = ? AND b = ?`, positionalParams(1, 2),
+			`SELECT * FROM t WHERE a = ? AND b = ?`, []any{1, 2}, ""},
+		{"mysql name reused", DriverMySQL, `SELECT :a, :a`, named("a", 1),
+			`SELECT ?, ?`, []any{1, 1}, ""},
+		{"mysql dollar", DriverMySQL, `SELECT $2, $1, $2`, positionalParams("a", "b"),
+			`SELECT ?, ?, ?`, []any{"b", "a", "b"}, ""},
+		{"sqlite numbered", DriverSQLite, `SELECT ?1, ?1, ?2`, positionalParams(1, 2),
+			`SELECT ?1, ?1, ?2`, []any{1, 2}, ""},
+		{"sqlite question", DriverSQLite, `SELECT ?, ?`, positionalParams(1, 2),
+			`SELECT ?1, ?2`, []any{1, 2}, ""},
+		{"sqlserver question", DriverSQLServer, `SELECT * FROM t WHERE a = ? AND b = ?`, positionalParams(1, 2),
+			`SELECT * FROM t WHERE a = @p1 AND b = @p2`, []any{1, 2}, ""},
+		{"sqlserver name reused", DriverSQLServer, `SELECT :a WHERE x = :a`, named("a", 1),
+			`SELECT @p1 WHERE x = @p1`, []any{1}, ""},
+		{"sqlserver dollar", DriverSQLServer, `SELECT $1`, positionalParams(1),
+			`SELECT @p1`, []any{1}, ""},
+		{"clickhouse question", DriverClickHouse, `SELECT ? + ?`, positionalParams(1, 2),
+			`SELECT $1 + $2`, []any{1, 2}, ""},
+		{"duckdb name", DriverDuckDB, `SELECT :n`, named("n", "x"),
+			`SELECT $1`, []any{"x"}, ""},
+
+		{"mixed styles", DriverMySQL, `SELECT $1, ?`, positionalParams(1, 2), "", nil, "mixes placeholder styles"},
+		{"mixed dollar and name", DriverPostgres, `SELECT $1, :a`, named("a", 1), "", nil, "mixes placeholder styles"},
+		{"mixed params", DriverPostgres, `SELECT :a`, append(named("a", 1), queryParam{Value: 2}), "", nil, "cannot mix named and positional"},
+		{"duplicate name", DriverPostgres, `SELECT :a`, named("a", 1, "a", 2), "", nil, "duplicate parameter name"},
+		{"no names in query", DriverPostgres, `SELECT $1`, named("a", 1), "", nil, "no :name placeholders"},
+		{"question count", DriverMySQL, `SELECT ?, ?`, positionalParams(1), "", nil, "2 ? placeholders but 1 params"},
+		{"dollar out of range", DriverPostgres, `SELECT $3`, positionalParams(1, 2), "", nil, "$3 has no matching param"},
+		{"dollar zero", DriverPostgres, `SELECT $0`, positionalParams(1), "", nil, "$0 has no matching param"},
+		{"unused positional", DriverPostgres, `SELECT $1`, positionalParams(1, 2), "", nil, "params[1] is not referenced"},
+		{"unused name", DriverPostgres, `SELECT :a`, named("a", 1, "b", 2), "", nil, "param b is not referenced"},
+		{"no placeholders", DriverPostgres, `SELECT 1`, positionalParams(1), "", nil, "params[0] is not referenced"},
+		{"lex error", DriverPostgres, `SELECT '`, positionalParams(1), "", nil, "unterminated"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, args, err := bindParams(tc.kind, tc.query, tc.params)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want || !reflect.DeepEqual(args, tc.args) {
+				t.Errorf("bindParams(%s, %q) = %q %v, want %q %v", tc.kind, tc.query, got, args, tc.want, tc.args)
+			}
+		})
+	}
+}
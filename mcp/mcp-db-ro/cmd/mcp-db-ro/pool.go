@@ -0,0 +1,319 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultMaxOpenConns     = 4
+	defaultConnMaxLifetime  = 30 * time.Minute
+	defaultMaxDatabasePools = 8
+
+	// poolEvictGrace keeps a database pool that was just handed out from
+	// being evicted before the call using it has taken a connection.
+	poolEvictGrace = 10 * time.Second
+)
+
+// PoolConfig sizes the pools of a connection. Each database pool of a
+// Postgres or SQL Server connection gets the same settings.
+type PoolConfig struct {
+	MaxOpenConns           int `json:"maxOpenConns,omitempty"`           // default 4
+	MaxIdleConns           int `json:"maxIdleConns,omitempty"`           // default maxOpenConns
+	ConnMaxLifetimeSeconds int `json:"connMaxLifetimeSeconds,omitempty"` // default 1800
+	ConnMaxIdleTimeSeconds int `json:"connMaxIdleTimeSeconds,omitempty"` // default no limit
+	// MaxDatabasePools bounds the extra pools opened for other databases
+	// than the configured one (default 8); the least recently used idle one
+	// is closed to make room.
+	MaxDatabasePools int `json:"maxDatabasePools,omitempty"`
+}
+
+func (p PoolConfig) validate() error {
+	switch {
+	case p.MaxOpenConns < 0, p.MaxIdleConns < 0, p.ConnMaxLifetimeSeconds < 0,
+		p.ConnMaxIdleTimeSeconds < 0, p.MaxDatabasePools < 0:
+		return errors.New("pool settings must not be negative")
+	case p.MaxIdleConns > p.maxOpen():
+		return fmt.Errorf("pool.maxIdleConns (%d) exceeds pool.maxOpenConns (%d)", p.MaxIdleConns, p.maxOpen())
+	}
+	return nil
+}
+
+func (p PoolConfig) maxOpen() int {
+	if p.MaxOpenConns > 0 {
+		return p.MaxOpenConns
+	}
+	return defaultMaxOpenConns
+}
+
+func (p PoolConfig) maxDatabasePools() int {
+	if p.MaxDatabasePools > 0 {
+		return p.MaxDatabasePools
+	}
+	return defaultMaxDatabasePools
+}
+
+// apply sets the pool settings on db.
+func (p PoolConfig) apply(db *sql.DB) {
+	idle := p.MaxIdleConns
+	if idle == 0 {
+		idle = p.maxOpen()
+	}
+	lifetime := defaultConnMaxLifetime
+	if p.ConnMaxLifetimeSeconds > 0 {
+		lifetime = time.Duration(p.ConnMaxLifetimeSeconds) * time.Second
+	}
+	db.SetMaxOpenConns(p.maxOpen())
+	db.SetMaxIdleConns(idle)
+	db.SetConnMaxLifetime(lifetime)
+	if p.ConnMaxIdleTimeSeconds > 0 {
+		db.SetConnMaxIdleTime(time.Duration(p.ConnMaxIdleTimeSeconds) * time.Second)
+	}
+}
+
+// PoolLimits caps all connections together: MaxPools counts pools (every
+// connection has one, plus one per extra database on Postgres and SQL
+// Server) and MaxConns the connections they may open (maxOpenConns each).
+// Zero means no limit. The connections' own pools must fit; extra database
+// pools are opened within what is left, closing idle ones as needed.
+type PoolLimits struct {
+	MaxPools int `json:"maxPools,omitempty"`
+	MaxConns int `json:"maxConns,omitempty"`
+}
+
+// check reports whether the connections of cfg fit the limits.
+func (l PoolLimits) check(cfg Config) error {
+	if l.MaxPools < 0 || l.MaxConns < 0 {
+		return errors.New("pools limits must not be negative")
+	}
+	conns := 0
+	for _, c := range cfg.Connections {
+		conns += c.Pool.maxOpen()
+	}
+	if l.MaxPools > 0 && len(cfg.Connections) > l.MaxPools {
+		return fmt.Errorf("pools.maxPools (%d) is below the number of connections (%d)", l.MaxPools, len(cfg.Connections))
+	}
+	if l.MaxConns > 0 && conns > l.MaxConns {
+		return fmt.Errorf("pools.maxConns (%d) is below the %d connections the configured pools may open", l.MaxConns, conns)
+	}
+	return nil
+}
+
+// dbPool is a pool for one database of a poolPerDatabase connection.
+type dbPool struct {
+	db       *sql.DB
+	lastUsed atomic.Int64 // unix nanoseconds
+}
+
+func (p *dbPool) touch() { p.lastUsed.Store(time.Now().UnixNano()) }
+
+// evictable reports whether p can be closed: it has no connection in use, no
+// cursor pages through it, and it was not handed out just now.
+func (p *dbPool) evictable(c *dbClient) bool {
+	return p.db.Stats().InUse == 0 &&
+		time.Since(time.Unix(0, p.lastUsed.Load())) >= poolEvictGrace &&
+		!c.cursors.uses(p.db)
+}
+
+// poolLimiter enforces the PoolLimits across the clients it tracks, which
+// include clients retired by a reload until they are closed.
+type poolLimiter struct {
+	mu       sync.Mutex
+	limits   PoolLimits
+	clients  map[*dbClient]bool
+	reserved map[*dbClient]int // database pools being opened
+}
+
+func newPoolLimiter(limits PoolLimits) *poolLimiter {
+	return &poolLimiter{limits: limits, clients: map[*dbClient]bool{}, reserved: map[*dbClient]int{}}
+}
+
+func (l *poolLimiter) setLimits(limits PoolLimits) {
+	l.mu.Lock()
+	l.limits = limits
+	l.mu.Unlock()
+}
+
+func (l *poolLimiter) register(c *dbClient) {
+	l.mu.Lock()
+	l.clients[c] = true
+	l.mu.Unlock()
+}
+
+func (l *poolLimiter) unregister(c *dbClient) {
+	l.mu.Lock()
+	delete(l.clients, c)
+	l.mu.Unlock()
+}
+
+// acquire makes room for a new database pool of c, closing the least
+// recently used idle database pools as needed: of c when it has
+// maxDatabasePools open, of any connection when the global limits are
+// reached. The caller must call release once the pool is in c.dbByDatabase
+// or failed to open.
+func (l *poolLimiter) acquire(c *dbClient) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for c.databasePools()+l.reserved[c] >= c.cfg.Pool.maxDatabasePools() {
+		if !l.evictLocked(c) {
+			return fmt.Errorf("connection %s has %d database pools open, all in use (pool.maxDatabasePools)", c.cfg.Name, c.cfg.Pool.maxDatabasePools())
+		}
+	}
+	for {
+		pools, conns := l.usageLocked()
+		poolsOK := l.limits.MaxPools == 0 || pools+1 <= l.limits.MaxPools
+		connsOK := l.limits.MaxConns == 0 || conns+c.cfg.Pool.maxOpen() <= l.limits.MaxConns
+		if poolsOK && connsOK {
+			break
+		}
+		if !l.evictLocked(nil) {
+			return fmt.Errorf("pool limits reached (%d pools, %d connections) and no idle database pool to close", pools, conns)
+		}
+	}
+	l.reserved[c]++
+	return nil
+}
+
+func (l *poolLimiter) release(c *dbClient) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.reserved[c]--; l.reserved[c] <= 0 {
+		delete(l.reserved, c)
+	}
+}
+
+// usageLocked counts the pools of the tracked clients and the connections
+// they may open, including database pools being opened.
+func (l *poolLimiter) usageLocked() (pools, conns int) {
+	for c := range l.clients {
+		n := 1 + c.databasePools() + l.reserved[c]
+		pools += n
+		conns += n * c.cfg.Pool.maxOpen()
+	}
+	return pools, conns
+}
+
+// evictLocked closes the least recently used evictable database pool, of only
+// if set. It reports whether there was one.
+func (l *poolLimiter) evictLocked(only *dbClient) bool {
+	var (
+		victim   *dbClient
+		database string
+		oldest   int64
+	)
+	for c := range l.clients {
+		if only != nil && c != only {
+			continue
+		}
+		c.mu.RLock()
+		for name, p := range c.dbByDatabase {
+			if used := p.lastUsed.Load(); (victim == nil || used < oldest) && p.evictable(c) {
+				victim, database, oldest = c, name, used
+			}
+		}
+		c.mu.RUnlock()
+	}
+	if victim == nil {
+		return false
+	}
+	victim.mu.Lock()
+	p := victim.dbByDatabase[database]
+	delete(victim.dbByDatabase, database)
+	victim.mu.Unlock()
+	if victim.logger != nil {
+		victim.logger.Printf("connection %s: closed idle pool for database %s", victim.cfg.Name, database)
+	}
+	go p.db.Close()
+	return true
+}
+
+// databasePools returns the number of open database pools.
+func (c *dbClient) databasePools() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.dbByDatabase)
+}
+
+// poolStats reports the pools of c for db.poolStats.
+func (c *dbClient) poolStats() []map[string]any {
+	name := c.cfg.Name
+	if c.docs != nil {
+		return []map[string]any{{"connection": name, "maxOpen": c.cfg.Pool.maxOpen(), "note": "pool statistics are not available for " + c.cfg.Driver}}
+	}
+	out := []map[string]any{poolRow(name, c.cfg.Database, c.db.Stats())}
+	c.mu.RLock()
+	databases := make([]string, 0, len(c.dbByDatabase))
+	for database := range c.dbByDatabase {
+		databases = append(databases, database)
+	}
+	sort.Strings(databases)
+	for _, database := range databases {
+		p := c.dbByDatabase[database]
+		row := poolRow(name, database, p.db.Stats())
+		row["lastUsed"] = time.Unix(0, p.lastUsed.Load()).UTC().Format(time.RFC3339)
+		out = append(out, row)
+	}
+	c.mu.RUnlock()
+	return out
+}
+
+func poolRow(connection, database string, st sql.DBStats) map[string]any {
+	row := map[string]any{
+		"connection":        connection,
+		"maxOpen":           st.MaxOpenConnections,
+		"open":              st.OpenConnections,
+		"inUse":             st.InUse,
+		"idle":              st.Idle,
+		"waitCount":         st.WaitCount,
+		"waitMs":            st.WaitDuration.Milliseconds(),
+		"maxIdleClosed":     st.MaxIdleClosed,
+		"maxIdleTimeClosed": st.MaxIdleTimeClosed,
+		"maxLifetimeClosed": st.MaxLifetimeClosed,
+	}
+	if database != "" {
+		row["database"] = database
+	}
+	return row
+}
+
+// poolStats reports the pools of one connection, or of all, with the usage
+// of the global limits.
+func (s *dbService) poolStats(conn string) (any, error) {
+	var clients []*dbClient
+	if conn != "" {
+		c, err := s.lookupClient(conn)
+		if err != nil {
+			return nil, err
+		}
+		clients = []*dbClient{c}
+	} else {
+		s.mu.RLock()
+		for _, c := range s.connections {
+			clients = append(clients, c)
+		}
+		s.mu.RUnlock()
+		sort.Slice(clients, func(i, j int) bool { return clients[i].cfg.Name < clients[j].cfg.Name })
+	}
+	rows := []map[string]any{}
+	for _, c := range clients {
+		rows = append(rows, c.poolStats()...)
+	}
+
+	l := s.pools
+	l.mu.Lock()
+	pools, conns := l.usageLocked()
+	limits := map[string]any{"pools": pools, "conns": conns}
+	if l.limits.MaxPools > 0 {
+		limits["maxPools"] = l.limits.MaxPools
+	}
+	if l.limits.MaxConns > 0 {
+		limits["maxConns"] = l.limits.MaxConns
+	}
+	l.mu.Unlock()
+	return map[string]any{"pools": rows, "limits": limits}, nil
+}
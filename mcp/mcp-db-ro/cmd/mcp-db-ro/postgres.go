@@ -5,12 +5,63 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
 type postgresDriver struct{}
 
+func init() {
+	registerDriver(driverSpec{
+		kind:            DriverPostgres,
+		aliases:         []string{"postgresql", "pg"},
+		sqlDriverName:   "pgx",
+		openWith:        postgresOpenWith,
+		newDriver:       func() DBDriver { return postgresDriver{} },
+		dsn:             postgresDSN,
+		credentials:     pgCredentials,
+		urlSchemes:      []string{"postgres", "postgresql"},
+		parseURL:        pgURL,
+		scope:           scopeRules{defaultSchema: "public"},
+		poolPerDatabase: true,
+		checkDatabase:   checkDatabasePool,
+		dialect:         postgresDialect,
+		placeholders:    placeholderDollar,
+	})
+}
+
 func (postgresDriver) Kind() DriverKind { return DriverPostgres }
 
+// postgresOpenWith opens a pgx pool through dial and with the TLS settings
+// pgx has no connection string keywords for. Host names are passed to dial
+// unresolved so that the SSH server resolves them.
+func postgresOpenWith(_ ConnectionConfig, dsn string, dial dialFunc, tls *tlsState) (*sql.DB, error) {
+	cc, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if dial != nil {
+		cc.DialFunc = pgconn.DialFunc(dial)
+		cc.LookupFunc = func(_ context.Context, host string) ([]string, error) {
+			return []string{host}, nil
+		}
+	}
+	if tls != nil {
+		if cc.TLSConfig != nil {
+			tls.apply(cc.TLSConfig)
+		}
+		for _, fb := range cc.Fallbacks {
+			if fb.TLSConfig != nil {
+				tls.apply(fb.TLSConfig)
+			}
+		}
+	}
+	return stdlib.OpenDB(*cc), nil
+}
+
 func (postgresDriver) ListDatabases(ctx context.Context, db *sql.DB) ([]map[string]any, error) {
 	return queryAll(ctx, db, `SELECT datname FROM pg_database WHERE datistemplate = false ORDER BY datname`)
 }
@@ -69,14 +120,44 @@ WHERE ns.nspname = $1 AND parent.relname = $2
 ORDER BY child.relname`, ref.Schema, ref.Table)
 }
 
-func (postgresDriver) Explain(ctx context.Context, db *sql.DB, query, format string) ([]map[string]any, error) {
+func (postgresDriver) Explain(ctx context.Context, db queryer, query, format string, args ...any) ([]map[string]any, error) {
 	f := strings.ToLower(strings.TrimSpace(format))
 	switch f {
 	case "", "text":
-		return queryAll(ctx, db, "EXPLAIN "+query)
+		return queryAll(ctx, db, "EXPLAIN "+query, args...)
 	case "json":
-		return queryAll(ctx, db, "EXPLAIN (FORMAT JSON) "+query)
+		return queryAll(ctx, db, "EXPLAIN (FORMAT JSON) "+query, args...)
 	default:
 		return nil, fmt.Errorf("unsupported format: %s (postgres supports: text, json)", format)
 	}
 }
+
+func (postgresDriver) SessionID(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var pid int64
+	err := conn.QueryRowContext(ctx, `SELECT pg_backend_pid()`).Scan(&pid)
+	return pid, err
+}
+
+func (postgresDriver) SetStatementTimeout(ctx context.Context, q queryer, timeout time.Duration, inTx bool) error {
+	scope := "SESSION"
+	if inTx {
+		scope = "LOCAL"
+	}
+	_, err := q.ExecContext(ctx, fmt.Sprintf("SET %s statement_timeout = %d", scope, timeout.Milliseconds()))
+	return err
+}
+
+func (postgresDriver) CancelSession(ctx context.Context, db *sql.DB, id int64) error {
+	_, err := db.ExecContext(ctx, `SELECT pg_cancel_backend($1)`, id)
+	return err
+}
+
+func (postgresDriver) DeclareCursor(ctx context.Context, q queryer, name, query string, args ...any) error {
+	_, err := q.ExecContext(ctx, "DECLARE "+quoteIdentPG(name)+" NO SCROLL CURSOR FOR\n"+query+"\n", args...)
+	return err
+}
+
+func (d postgresDriver) FetchCursor(ctx context.Context, q queryer, name string, n int) (resultSet, error) {
+	rs, _, err := queryPage(ctx, q, d, fmt.Sprintf("FETCH FORWARD %d FROM %s", n, quoteIdentPG(name)), 0, 0)
+	return rs, err
+}
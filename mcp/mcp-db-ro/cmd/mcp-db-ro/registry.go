@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// driverSpec is everything the server needs to know about a driver kind. Each
+// driver registers its spec from its own file, so adding an engine does not
+// touch the shared code.
+type driverSpec struct {
+	kind DriverKind
+	// aliases are other accepted spellings of the driver in the config.
+	aliases []string
+
+	// sqlDriverName is the database/sql driver to open, unless the DBDriver
+	// implements dbOpener.
+	sqlDriverName string
+	// openWith opens a pool with settings a DSN cannot carry: connections
+	// dialed by dial (an SSH tunnel) and TLS settings. Either may be nil;
+	// drivers without it support neither.
+	openWith func(cfg ConnectionConfig, dsn string, dial dialFunc, tls *tlsState) (*sql.DB, error)
+	// newDriver returns the DBDriver; nil for document databases.
+	newDriver func() DBDriver
+	// newDocStore connects a document database.
+	newDocStore func(cfg ConnectionConfig) (docStore, error)
+	// dsn builds the connection string (DSN or URI) for cfg.
+	dsn func(cfg ConnectionConfig) (string, error)
+	// credentials fills in cfg from the driver's own credential sources
+	// (service and option files) when the config is read.
+	credentials func(cfg *ConnectionConfig) error
+	// parseURL splits a connection URL with one of urlSchemes into fields.
+	urlSchemes []string
+	parseURL   func(raw string) (ConnectionConfig, error)
+
+	scope scopeRules
+	// poolPerDatabase is set when a connection is bound to one database
+	// (Postgres, SQL Server): other databases get a pool of their own.
+	poolPerDatabase bool
+	// checkDatabase validates the target of db.useDatabase. note, if set, is
+	// returned with the selection.
+	checkDatabase func(ctx context.Context, c *dbClient, database string) (note string, err error)
+
+	dialect      sqlDialect
+	placeholders placeholderStyle
+}
+
+// scopeRules fill in the database and schema a tool call left out. The
+// database is the selected one, else defaultDatabase (when
+// useDefaultDatabase), else the configured database, else fallbackDatabase.
+type scopeRules struct {
+	useDefaultDatabase bool
+	fallbackDatabase   string
+	// defaultSchema fills an empty schema; noSchema clears it for drivers
+	// without a schema level.
+	defaultSchema string
+	noSchema      bool
+	// requireDatabase makes table tools fail without a database.
+	requireDatabase bool
+}
+
+// placeholderStyle is the native bind parameter syntax of a driver.
+type placeholderStyle int
+
+const (
+	placeholderDollar           placeholderStyle = iota // $1, $2, ...
+	placeholderQuestion                                 // ?, one per argument
+	placeholderNumberedQuestion                         // ?1, ?2, ...
+	placeholderAtP                                      // @p1, @p2, ...
+)
+
+var (
+	drivers       = map[DriverKind]*driverSpec{}
+	driverAliases = map[string]*driverSpec{}
+)
+
+// registerDriver adds spec to the registry. It is called from init functions
+// and panics on duplicate kinds or aliases.
+func registerDriver(spec driverSpec) {
+	s := &spec
+	if _, dup := drivers[s.kind]; dup {
+		panic("driver registered twice: " + string(s.kind))
+	}
+	drivers[s.kind] = s
+	for _, name := range append([]string{string(s.kind)}, s.aliases...) {
+		if _, dup := driverAliases[name]; dup {
+			panic("driver alias registered twice: " + name)
+		}
+		driverAliases[name] = s
+	}
+}
+
+// lookupDriver returns the spec for a configured driver name or alias.
+func lookupDriver(name string) (*driverSpec, error) {
+	if s, ok := driverAliases[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return s, nil
+	}
+	kinds := make([]string, 0, len(drivers))
+	for k := range drivers {
+		kinds = append(kinds, string(k))
+	}
+	sort.Strings(kinds)
+	return nil, fmt.Errorf("unsupported driver: %s (supported: %s)", name, strings.Join(kinds, ", "))
+}
+
+func sqlDialectFor(kind DriverKind) sqlDialect {
+	if s, ok := drivers[kind]; ok && s.dialect.name != "" {
+		return s.dialect
+	}
+	return postgresDialect
+}
+
+func placeholdersFor(kind DriverKind) placeholderStyle {
+	if s, ok := drivers[kind]; ok {
+		return s.placeholders
+	}
+	return placeholderDollar
+}
+
+// checkDatabasePool validates a database by opening (and caching) its pool.
+func checkDatabasePool(ctx context.Context, c *dbClient, database string) (string, error) {
+	_, err := c.dbForDatabase(ctx, database)
+	return "", err
+}
+
+// checkDatabaseQuery returns a checkDatabase that runs query with the
+// database name as its only argument and fails if it returns no rows.
+func checkDatabaseQuery(query, notFound string) func(context.Context, *dbClient, string) (string, error) {
+	return func(ctx context.Context, c *dbClient, database string) (string, error) {
+		res, err := queryAll(ctx, c.db, query, database)
+		if err != nil {
+			return "", err
+		}
+		if len(res) == 0 {
+			return "", fmt.Errorf("%s: %s", notFound, database)
+		}
+		return "", nil
+	}
+}
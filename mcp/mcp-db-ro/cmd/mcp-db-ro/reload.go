@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// configPollInterval is how often the config file is checked for changes.
+const configPollInterval = 2 * time.Second
+
+// reloadSummary lists the connections a reload touched, by name.
+type reloadSummary struct {
+	Added     []string `json:"added,omitempty"`
+	Removed   []string `json:"removed,omitempty"`
+	Changed   []string `json:"changed,omitempty"`
+	Unchanged []string `json:"unchanged,omitempty"`
+	Export    bool     `json:"exportChanged,omitempty"`
+	Pools     bool     `json:"poolsChanged,omitempty"`
+}
+
+func (r reloadSummary) empty() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Changed) == 0 && !r.Export && !r.Pools
+}
+
+func (r reloadSummary) String() string {
+	if r.empty() {
+		return "config reloaded: no changes"
+	}
+	var parts []string
+	for _, p := range []struct {
+		label string
+		names []string
+	}{{"added", r.Added}, {"removed", r.Removed}, {"changed", r.Changed}} {
+		if len(p.names) > 0 {
+			parts = append(parts, p.label+" "+strings.Join(p.names, ", "))
+		}
+	}
+	if r.Export {
+		parts = append(parts, "export settings changed")
+	}
+	if r.Pools {
+		parts = append(parts, "pool limits changed")
+	}
+	return "config reloaded: " + strings.Join(parts, "; ")
+}
+
+// callEpoch counts the tool calls started between two reloads, so clients a
+// reload retires are closed only once the calls that may use them are done.
+type callEpoch struct {
+	wg sync.WaitGroup
+}
+
+// beginCall registers a tool call; the returned function ends it.
+func (s *dbService) beginCall() func() {
+	s.mu.RLock()
+	e := s.epoch
+	e.wg.Add(1)
+	s.mu.RUnlock()
+	return e.wg.Done
+}
+
+// reload applies cfg. Connections whose settings are unchanged keep their
+// pools, cursors and selected database; added and changed ones are set up
+// first (they connect on first use), and if any of them is invalid the reload
+// is abandoned with the current connections left in place. Removed and replaced clients are closed in the
+// background once the tool calls that started before the swap have finished.
+func (s *dbService) reload(cfg Config) (reloadSummary, error) {
+	var sum reloadSummary
+	if err := checkConnectionNames(cfg); err != nil {
+		return sum, err
+	}
+	if err := cfg.Pools.check(cfg); err != nil {
+		return sum, err
+	}
+
+	s.mu.RLock()
+	current := s.connections
+	exportChanged := s.export != cfg.Export
+	s.mu.RUnlock()
+	s.pools.mu.Lock()
+	sum.Pools = s.pools.limits != cfg.Pools
+	s.pools.mu.Unlock()
+
+	next := make(map[string]*dbClient, len(cfg.Connections))
+	opened := map[string]*dbClient{}
+	for _, c := range cfg.Connections {
+		old, ok := current[c.Name]
+		if ok && sameConnection(old.cfg, c) {
+			next[c.Name] = old
+			sum.Unchanged = append(sum.Unchanged, c.Name)
+			continue
+		}
+		client, err := newClient(s.logger, s.pools, c)
+		if err != nil {
+			for _, o := range opened {
+				o.close()
+			}
+			return reloadSummary{}, fmt.Errorf("connection %s: %w", c.Name, err)
+		}
+		next[c.Name] = client
+		opened[c.Name] = client
+		if ok {
+			sum.Changed = append(sum.Changed, c.Name)
+		} else {
+			sum.Added = append(sum.Added, c.Name)
+		}
+	}
+
+	var retired []*dbClient
+	for name, old := range current {
+		if next[name] != old {
+			retired = append(retired, old)
+			if _, ok := next[name]; !ok {
+				sum.Removed = append(sum.Removed, name)
+			}
+		}
+	}
+	sum.Export = exportChanged
+	for _, names := range [][]string{sum.Added, sum.Removed, sum.Changed, sum.Unchanged} {
+		sort.Strings(names)
+	}
+
+	s.mu.Lock()
+	s.connections = next
+	s.export = cfg.Export
+	s.pools.setLimits(cfg.Pools)
+	epoch := s.epoch
+	s.epoch = &callEpoch{}
+	s.mu.Unlock()
+
+	if len(retired) > 0 {
+		go func() {
+			epoch.wg.Wait()
+			for _, old := range retired {
+				old.close()
+			}
+		}()
+	}
+	return sum, nil
+}
+
+// sameConnection reports whether a connection can be kept across a reload.
+// cur is the config of a live client, whose driver name is normalized.
+func sameConnection(cur, next ConnectionConfig) bool {
+	if spec, err := lookupDriver(next.Driver); err == nil {
+		next.Driver = string(spec.kind)
+	}
+	return reflect.DeepEqual(cur, next)
+}
+
+// watchConfig reloads the config at path whenever the file changes (checked
+// every configPollInterval) or the process receives SIGHUP, and reports each
+// outcome to notify. A config that fails to read or apply leaves the running
+// connections untouched. The returned function stops watching.
+func watchConfig(s *dbService, path string, notify func(reloadSummary, error)) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	last, _ := os.Stat(path)
+	apply := func() {
+		cfg, err := readConfig(path)
+		if err != nil {
+			notify(reloadSummary{}, fmt.Errorf("read config: %w", err))
+			return
+		}
+		sum, err := s.reload(cfg)
+		notify(sum, err)
+	}
+
+	go func() {
+		defer signal.Stop(hup)
+		ticker := time.NewTicker(configPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				last, _ = os.Stat(path)
+				apply()
+			case <-ticker.C:
+				fi, err := os.Stat(path)
+				if err != nil || fileUnchanged(last, fi) {
+					continue
+				}
+				last = fi
+				apply()
+			}
+		}
+	}()
+	return cancel
+}
+
+// fileUnchanged compares identity, size and modification time, so editors
+// that save by renaming a new file into place are noticed too.
+func fileUnchanged(a, b os.FileInfo) bool {
+	return a != nil && b != nil && os.SameFile(a, b) && a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
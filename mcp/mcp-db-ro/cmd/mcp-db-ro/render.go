@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Text renderings of tool results. The structured content of a result is
+// always JSON; format only changes the text content.
+const (
+	formatJSON     = "json"
+	formatCSV      = "csv"
+	formatMarkdown = "markdown"
+	formatNDJSON   = "ndjson"
+)
+
+// table is a tabular view of a tool result used by the non-JSON formats.
+type table struct {
+	columns []string
+	rows    [][]any
+	// footer is a note about the result (e.g. paging) shown in Markdown.
+	footer string
+}
+
+func checkFormat(format string) error {
+	switch format {
+	case formatJSON, formatCSV, formatMarkdown, formatNDJSON:
+		return nil
+	}
+	return fmt.Errorf("unsupported format: %s (use json, csv, markdown or ndjson)", format)
+}
+
+// renderText renders v in format.
+func renderText(v any, format string) (string, error) {
+	if err := checkFormat(format); err != nil {
+		return "", err
+	}
+	if format == formatJSON {
+		pretty, err := json.MarshalIndent(v, "", "  ")
+		return string(pretty), err
+	}
+	t, err := tableOf(v)
+	if err != nil {
+		return "", err
+	}
+	switch format {
+	case formatCSV:
+		return t.csv()
+	case formatMarkdown:
+		return t.markdown()
+	default:
+		return t.ndjson()
+	}
+}
+
+func tableOf(v any) (table, error) {
+	switch x := v.(type) {
+	case queryResult:
+		t := table{columns: x.columnNames(), footer: x.pagingNote()}
+		switch rows := x.Rows.(type) {
+		case [][]any:
+			t.rows = rows
+		case []map[string]any:
+			t.rows = mapRows(t.columns, rows)
+		}
+		return t, nil
+	case []map[string]any:
+		cols := mapColumns(x)
+		return table{columns: cols, rows: mapRows(cols, x)}, nil
+	case []string:
+		t := table{columns: []string{"name"}}
+		for _, s := range x {
+			t.rows = append(t.rows, []any{s})
+		}
+		return t, nil
+	}
+	return table{}, fmt.Errorf("result cannot be rendered as a table; use format json")
+}
+
+func (r queryResult) columnNames() []string {
+	cols := r.Columns
+	if cols == nil {
+		cols = r.columns
+	}
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.Name
+	}
+	return names
+}
+
+func (r queryResult) pagingNote() string {
+	if !r.Truncated {
+		return fmt.Sprintf("%d rows", r.RowsRead)
+	}
+	note := fmt.Sprintf("%d rows so far; more available", r.RowsRead)
+	if r.NextCursor != "" {
+		note += fmt.Sprintf(" (nextCursor %s, expires %s)", r.NextCursor, r.CursorExpiresAt)
+	}
+	return note
+}
+
+// mapColumns returns the union of the rows' keys, sorted (maps do not keep
+// column order).
+func mapColumns(rows []map[string]any) []string {
+	seen := map[string]bool{}
+	var cols []string
+	for _, m := range rows {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				cols = append(cols, k)
+			}
+		}
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+func mapRows(cols []string, rows []map[string]any) [][]any {
+	out := make([][]any, len(rows))
+	for i, m := range rows {
+		row := make([]any, len(cols))
+		for j, c := range cols {
+			row[j] = m[c]
+		}
+		out[i] = row
+	}
+	return out
+}
+
+// cellText renders a value for CSV and Markdown: strings as-is, NULL as
+// null, everything else as compact JSON.
+func cellText(v any, null string) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return null, nil
+	case string:
+		return x, nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func (t table) csv() (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(t.columns); err != nil {
+		return "", err
+	}
+	record := make([]string, len(t.columns))
+	for _, row := range t.rows {
+		for i, v := range row {
+			s, err := cellText(v, "")
+			if err != nil {
+				return "", err
+			}
+			record[i] = s
+		}
+		if err := w.Write(record); err != nil {
+			return "", err
+		}
+	}
+	w.Flush()
+	return buf.String(), w.Error()
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+func (t table) markdown() (string, error) {
+	if len(t.columns) == 0 {
+		return "_no rows_\n", nil
+	}
+	var b strings.Builder
+	writeRow := func(cells []string) {
+		b.WriteString("|")
+		for _, c := range cells {
+			b.WriteString(" ")
+			b.WriteString(markdownEscaper.Replace(c))
+			b.WriteString(" |")
+		}
+		b.WriteString("\n")
+	}
+	writeRow(t.columns)
+	sep := make([]string, len(t.columns))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	writeRow(sep)
+	cells := make([]string, len(t.columns))
+	for _, row := range t.rows {
+		for i, v := range row {
+			s, err := cellText(v, "NULL")
+			if err != nil {
+				return "", err
+			}
+			cells[i] = s
+		}
+		writeRow(cells)
+	}
+	if t.footer != "" {
+		b.WriteString("\n_" + t.footer + "_\n")
+	}
+	return b.String(), nil
+}
+
+// ndjson writes one JSON object per row, keys in column order.
+func (t table) ndjson() (string, error) {
+	var b bytes.Buffer
+	for _, row := range t.rows {
+		if err := appendNDJSON(&b, t.columns, row); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+// appendNDJSON writes row as one JSON object line with keys in column order.
+func appendNDJSON(b *bytes.Buffer, columns []string, row []any) error {
+	b.WriteString("{")
+	for i, v := range row {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		k, err := json.Marshal(columns[i])
+		if err != nil {
+			return err
+		}
+		val, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		b.Write(k)
+		b.WriteString(":")
+		b.Write(val)
+	}
+	b.WriteString("}\n")
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// sessionKey carries the MCP session ID of a tool call.
+type sessionKey struct{}
+
+// withSession returns ctx for a tool call made in session id. State kept per
+// session, such as the database selected with db.useDatabase, is keyed by it.
+func withSession(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, id)
+}
+
+// sessionFrom returns the session ID of ctx; calls outside a session share
+// the empty one.
+func sessionFrom(ctx context.Context) string {
+	id, _ := ctx.Value(sessionKey{}).(string)
+	return id
+}
+
+// endSession forgets what the session id selected on every connection.
+func (s *dbService) endSession(id string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, c := range s.connections {
+		c.endSession(id)
+	}
+}
+
+// currentContext reports, for the session of ctx, the database and schema
+// tools use when none is given: on the named connection, or on all.
+func (s *dbService) currentContext(ctx context.Context, conn string) ([]map[string]any, error) {
+	var clients []*dbClient
+	if strings.TrimSpace(conn) != "" {
+		c, err := s.lookupClient(conn)
+		if err != nil {
+			return nil, err
+		}
+		clients = []*dbClient{c}
+	} else {
+		s.mu.RLock()
+		for _, c := range s.connections {
+			clients = append(clients, c)
+		}
+		s.mu.RUnlock()
+		sort.Slice(clients, func(i, j int) bool { return clients[i].cfg.Name < clients[j].cfg.Name })
+	}
+	out := make([]map[string]any, 0, len(clients))
+	for _, c := range clients {
+		row := map[string]any{"connection": c.cfg.Name, "driver": c.cfg.Driver}
+		selected := c.getSelectedDatabase(ctx)
+		scope, err := c.normalizeScope(ctx, TableScope{})
+		if err != nil {
+			return nil, err
+		}
+		if scope.Database != "" {
+			row["database"] = scope.Database
+		}
+		if scope.Schema != "" {
+			row["schema"] = scope.Schema
+		}
+		switch {
+		case selected != "":
+			row["source"] = "session"
+		case strings.TrimSpace(c.cfg.Database) != "" || strings.TrimSpace(c.cfg.DefaultDatabase) != "":
+			row["source"] = "config"
+		case scope.Database != "":
+			row["source"] = "driver default"
+		default:
+			row["source"] = "server default"
+		}
+		out = append(out, row)
+	}
+	return out, nil
+}
@@ -0,0 +1,810 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sqlDialect captures the lexical rules the read-only guard needs in order to
+// find statement boundaries and keywords without being fooled by literals,
+// quoted identifiers or comments.
+type sqlDialect struct {
+	name string
+
+	backslashEscapes    bool // backslash escapes the next character in string literals (MySQL)
+	doubleQuoteStrings  bool // "..." is a string literal, not an identifier (MySQL)
+	backtickIdents      bool // `ident` (MySQL)
+	dollarQuotes        bool // $tag$ ... $tag$ (Postgres)
+	escapeStrings       bool // E'...' enables backslash escapes (Postgres)
+	nestedComments      bool // /* /* */ */ nests (Postgres)
+	hashComments        bool // # starts a line comment (MySQL)
+	dashNeedsSpace      bool // "--" only starts a comment when followed by whitespace (MySQL)
+	executableComments  bool // /*! ... */ is executed by the server (MySQL/MariaDB)
+	bracketIdents       bool // [ident] (SQLite, SQL Server)
+	numberedQuestions   bool // ?NNN is a numbered parameter (SQLite)
+	questionOperator    bool // ? is also an operator (jsonb key exists, Postgres)
+	caseSensitiveQuoted bool // quoted identifiers are not case-folded (Postgres)
+	// unseparatedBatches: statements in a batch need no ";" between them
+	// (SQL Server), so statement keywords are refused anywhere.
+	unseparatedBatches bool
+	// escapesMayBeOff: the server can run with backslash escapes off
+	// (NO_BACKSLASH_ESCAPES, MySQL), so a string holding \' or \" could end
+	// earlier than the lexer thinks; such strings are refused.
+	escapesMayBeOff bool
+}
+
+var (
+	postgresDialect = sqlDialect{
+		name:                "postgres",
+		dollarQuotes:        true,
+		escapeStrings:       true,
+		nestedComments:      true,
+		questionOperator:    true,
+		caseSensitiveQuoted: true,
+	}
+	mysqlDialect = sqlDialect{
+		name:               "mysql",
+		backslashEscapes:   true,
+		escapesMayBeOff:    true,
+		doubleQuoteStrings: true,
+		backtickIdents:     true,
+		hashComments:       true,
+		dashNeedsSpace:     true,
+		executableComments: true,
+	}
+	sqliteDialect = sqlDialect{
+		name:              "sqlite",
+		backtickIdents:    true,
+		bracketIdents:     true,
+		numberedQuestions: true,
+	}
+	sqlserverDialect = sqlDialect{
+		name:               "sqlserver",
+		bracketIdents:      true,
+		nestedComments:     true,
+		unseparatedBatches: true,
+	}
+	clickhouseDialect = sqlDialect{
+		name:             "clickhouse",
+		backslashEscapes: true,
+		backtickIdents:   true,
+		hashComments:     true,
+	}
+	duckdbDialect = sqlDialect{
+		name:           "duckdb",
+		dollarQuotes:   true,
+		escapeStrings:  true,
+		nestedComments: true,
+	}
+)
+
+type sqlTokenKind int
+
+const (
+	tokWord sqlTokenKind = iota
+	tokQuotedIdent
+	tokString
+	tokNumber
+	tokParam
+	tokPunct
+)
+
+type sqlToken struct {
+	kind  sqlTokenKind
+	text  string
+	upper string // upper-cased text for tokWord
+	pos   int
+}
+
+func (t sqlToken) isWord(words ...string) bool {
+	if t.kind != tokWord {
+		return false
+	}
+	for _, w := range words {
+		if t.upper == w {
+			return true
+		}
+	}
+	return false
+}
+
+func (t sqlToken) isPunct(p string) bool {
+	return t.kind == tokPunct && t.text == p
+}
+
+// Block codes reported in sqlBlockError.Code.
+const (
+	blockEmpty              = "empty_query"
+	blockLexError           = "lex_error"
+	blockExecutableComment  = "executable_comment"
+	blockMultipleStatements = "multiple_statements"
+	blockStatementType      = "statement_not_allowed"
+	blockExplainTarget      = "explain_target_not_allowed"
+	blockDataModifying      = "data_modifying_statement"
+	blockSelectInto         = "select_into"
+	blockLockingClause      = "locking_clause"
+	blockSideEffectFunction = "side_effect_function"
+)
+
+// sqlBlockError explains why a query was rejected by the read-only guard.
+type sqlBlockError struct {
+	Code    string `json:"code"`
+	Reason  string `json:"reason"`
+	Token   string `json:"token,omitempty"`
+	Offset  int    `json:"offset"`
+	Dialect string `json:"dialect"`
+}
+
+func (e *sqlBlockError) Error() string {
+	return fmt.Sprintf("query blocked (%s): %s", e.Code, e.Reason)
+}
+
+// checkReadOnlySQL lexes q using the rules of the driver's dialect and returns a
+// *sqlBlockError unless it is exactly one statement that cannot modify data.
+func checkReadOnlySQL(kind DriverKind, q string) error {
+	d := sqlDialectFor(kind)
+	tokens, err := lexSQL(d, q)
+	if err != nil {
+		return err
+	}
+	stmts := splitStatements(tokens)
+	switch len(stmts) {
+	case 0:
+		return d.block(blockEmpty, "query is empty", sqlToken{})
+	case 1:
+	default:
+		return d.block(blockMultipleStatements, "only a single statement is allowed", stmts[1][0])
+	}
+	return d.classifyStatement(stmts[0])
+}
+
+// leadingKeyword returns the upper-cased first keyword of q, skipping comments
+// and opening parentheses, or "" if q cannot be lexed.
+func leadingKeyword(kind DriverKind, q string) string {
+	tokens, err := lexSQL(sqlDialectFor(kind), q)
+	if err != nil {
+		return ""
+	}
+	for _, t := range tokens {
+		if t.isPunct("(") {
+			continue
+		}
+		return t.upper
+	}
+	return ""
+}
+
+// trimStatement strips trailing semicolons (and anything after them that the
+// lexer skips, such as comments) so q can be embedded in a larger statement.
+func trimStatement(kind DriverKind, q string) string {
+	tokens, err := lexSQL(sqlDialectFor(kind), q)
+	if err != nil {
+		return q
+	}
+	end := len(tokens)
+	for end > 0 && tokens[end-1].isPunct(";") {
+		end--
+	}
+	if end == len(tokens) {
+		return strings.TrimSpace(q)
+	}
+	return strings.TrimSpace(q[:tokens[end].pos])
+}
+
+func (d sqlDialect) block(code, reason string, tok sqlToken) *sqlBlockError {
+	return &sqlBlockError{Code: code, Reason: reason, Token: tok.text, Offset: tok.pos, Dialect: d.name}
+}
+
+func splitStatements(tokens []sqlToken) [][]sqlToken {
+	var out [][]sqlToken
+	start := 0
+	for i, t := range tokens {
+		if t.isPunct(";") {
+			if i > start {
+				out = append(out, tokens[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if start < len(tokens) {
+		out = append(out, tokens[start:])
+	}
+	return out
+}
+
+// writeKeywords may introduce a data-modifying statement nested inside an
+// otherwise read-only one (e.g. a Postgres data-modifying CTE).
+var writeKeywords = map[string]bool{
+	"INSERT": true,
+	"UPDATE": true,
+	"DELETE": true,
+	"MERGE":  true,
+}
+
+// sideEffectFunctions have effects outside the current transaction, execute
+// SQL passed as a string, or read server-side files.
+var sideEffectFunctions = map[string]bool{
+	// Postgres
+	"PG_TERMINATE_BACKEND":                true,
+	"PG_CANCEL_BACKEND":                   true,
+	"PG_RELOAD_CONF":                      true,
+	"PG_ROTATE_LOGFILE":                   true,
+	"PG_PROMOTE":                          true,
+	"PG_SWITCH_WAL":                       true,
+	"PG_CREATE_RESTORE_POINT":             true,
+	"PG_BACKUP_START":                     true,
+	"PG_BACKUP_STOP":                      true,
+	"PG_START_BACKUP":                     true,
+	"PG_STOP_BACKUP":                      true,
+	"PG_CREATE_LOGICAL_REPLICATION_SLOT":  true,
+	"PG_CREATE_PHYSICAL_REPLICATION_SLOT": true,
+	"PG_DROP_REPLICATION_SLOT":            true,
+	"PG_LOGICAL_EMIT_MESSAGE":             true,
+	"PG_NOTIFY":                           true,
+	"PG_ADVISORY_LOCK":                    true,
+	"PG_ADVISORY_LOCK_SHARED":             true,
+	"PG_ADVISORY_XACT_LOCK":               true,
+	"PG_ADVISORY_XACT_LOCK_SHARED":        true,
+	"PG_TRY_ADVISORY_LOCK":                true,
+	"PG_TRY_ADVISORY_LOCK_SHARED":         true,
+	"PG_TRY_ADVISORY_XACT_LOCK":           true,
+	"PG_TRY_ADVISORY_XACT_LOCK_SHARED":    true,
+	"PG_READ_FILE":                        true,
+	"PG_READ_BINARY_FILE":                 true,
+	"PG_LS_DIR":                           true,
+	"PG_FILE_WRITE":                       true,
+	"SET_CONFIG":                          true,
+	"NEXTVAL":                             true,
+	"SETVAL":                              true,
+	"LO_IMPORT":                           true,
+	"LO_EXPORT":                           true,
+	"LO_UNLINK":                           true,
+	"LO_CREATE":                           true,
+	"LO_FROM_BYTEA":                       true,
+	"LO_PUT":                              true,
+	"DBLINK":                              true,
+	"DBLINK_EXEC":                         true,
+	"DBLINK_CONNECT":                      true,
+	"DBLINK_SEND_QUERY":                   true,
+	"QUERY_TO_XML":                        true,
+	"QUERY_TO_XML_AND_XMLSCHEMA":          true,
+	"QUERY_TO_XMLSCHEMA":                  true,
+	"CURSOR_TO_XML":                       true,
+	// MySQL
+	"SLEEP":             true,
+	"BENCHMARK":         true,
+	"GET_LOCK":          true,
+	"RELEASE_LOCK":      true,
+	"RELEASE_ALL_LOCKS": true,
+	"LOAD_FILE":         true,
+	"MASTER_POS_WAIT":   true,
+	"SOURCE_POS_WAIT":   true,
+	"SYS_EXEC":          true,
+	"SYS_EVAL":          true,
+	// SQLite
+	"LOAD_EXTENSION": true,
+	"READFILE":       true,
+	"WRITEFILE":      true,
+	"EDIT":           true,
+	// SQL Server
+	"OPENROWSET":     true,
+	"OPENDATASOURCE": true,
+	"OPENQUERY":      true,
+	// ClickHouse table functions that read files, run programs or reach other
+	// servers (allow_introspection_functions=0 is also sent with every
+	// query, see clickhouseDSN).
+	"FILE":                    true,
+	"FILECLUSTER":             true,
+	"URL":                     true,
+	"URLCLUSTER":              true,
+	"S3":                      true,
+	"S3CLUSTER":               true,
+	"GCS":                     true,
+	"AZUREBLOBSTORAGE":        true,
+	"AZUREBLOBSTORAGECLUSTER": true,
+	"HDFS":                    true,
+	"HDFSCLUSTER":             true,
+	"DELTALAKE":               true,
+	"DELTALAKECLUSTER":        true,
+	"HUDI":                    true,
+	"ICEBERG":                 true,
+	"ICEBERGS3":               true,
+	"ICEBERGAZURE":            true,
+	"ICEBERGHDFS":             true,
+	"ICEBERGLOCAL":            true,
+	"EXECUTABLE":              true,
+	"REMOTE":                  true,
+	"REMOTESECURE":            true,
+	"CLUSTER":                 true,
+	"CLUSTERALLREPLICAS":      true,
+	"MYSQL":                   true,
+	"POSTGRESQL":              true,
+	"SQLITE":                  true,
+	"MONGODB":                 true,
+	"REDIS":                   true,
+	"JDBC":                    true,
+	"ODBC":                    true,
+	"INPUT":                   true,
+	// DuckDB (file access is confined by allowed_directories; query runs SQL
+	// passed as a string)
+	"QUERY": true,
+}
+
+// sqlserverLockHints are table hints that take update or exclusive locks.
+var sqlserverLockHints = map[string]bool{
+	"UPDLOCK":  true,
+	"XLOCK":    true,
+	"TABLOCKX": true,
+	"HOLDLOCK": true,
+}
+
+// sqlserverStatementKeywords start a T-SQL statement. Since a batch may hold
+// several statements without separators, they are refused anywhere in a
+// query (all are reserved words, so a column with such a name must be quoted
+// anyway), also when followed by "(": EXEC('...') runs a string as a batch.
+var sqlserverStatementKeywords = map[string]bool{
+	"ALTER": true, "BACKUP": true, "BEGIN": true, "BREAK": true, "BULK": true,
+	"CHECKPOINT": true, "CLOSE": true, "COMMIT": true, "CONTINUE": true,
+	"CREATE": true, "DBCC": true, "DEALLOCATE": true, "DECLARE": true,
+	"DENY": true, "DROP": true, "DUMP": true, "EXEC": true, "EXECUTE": true,
+	"FETCH": true, "GOTO": true, "GRANT": true, "IF": true, "KILL": true,
+	"LOAD": true, "OPEN": true, "PRINT": true, "RAISERROR": true,
+	"READTEXT": true, "RECONFIGURE": true, "RESTORE": true, "RETURN": true,
+	"REVERT": true, "REVOKE": true, "ROLLBACK": true, "SAVE": true, "SET": true,
+	"SETUSER": true, "SHUTDOWN": true, "TRUNCATE": true, "UPDATETEXT": true,
+	"USE": true, "WAITFOR": true, "WHILE": true, "WRITETEXT": true,
+}
+
+func (d sqlDialect) classifyStatement(stmt []sqlToken) error {
+	i := 0
+	for i < len(stmt) && stmt[i].isPunct("(") {
+		i++
+	}
+	if i == len(stmt) {
+		return d.block(blockStatementType, "statement has no keyword", stmt[0])
+	}
+	first := stmt[i]
+	switch {
+	case first.isWord("SELECT", "WITH", "VALUES", "TABLE", "SHOW"):
+	case first.isWord("DESCRIBE", "DESC") && (d.name == "mysql" || d.name == "clickhouse"):
+	case first.isWord("DESCRIBE", "SUMMARIZE", "FROM") && d.name == "duckdb":
+		// FROM tbl [SELECT ...] is DuckDB's FROM-first query syntax.
+	case first.isWord("EXPLAIN"):
+		if err := d.checkExplainTarget(stmt[i+1:]); err != nil {
+			return err
+		}
+	default:
+		return d.block(blockStatementType,
+			fmt.Sprintf("%s statements are not allowed (only SELECT/WITH/VALUES/TABLE/SHOW/EXPLAIN)", strings.ToUpper(first.text)), first)
+	}
+
+	for j, t := range stmt {
+		next := tokenAt(stmt, j+1)
+		call := next.isPunct("(")
+		if t.kind == tokQuotedIdent {
+			// A quoted name is never a keyword, but it can still name a
+			// function: "pg_read_file"(...).
+			if !call {
+				continue
+			}
+			if name := d.unquoteIdent(t.text); name != "" {
+				if err := d.checkCall(sqlToken{kind: t.kind, text: t.text, upper: name, pos: t.pos}, true); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if t.kind != tokWord {
+			continue
+		}
+		prev := sqlToken{kind: tokPunct}
+		if j > 0 {
+			prev = stmt[j-1]
+		}
+		qualified := prev.isPunct(".")
+		alias := prev.isWord("AS") && !call // SELECT 1 AS update
+
+		switch {
+		case t.upper == "INTO":
+			return d.block(blockSelectInto, "INTO (SELECT INTO / INTO OUTFILE / INTO @var) writes data", t)
+		case t.upper == "FOR" && isLockingClause(stmt[j+1:]):
+			return d.block(blockLockingClause, "row locking clauses (FOR UPDATE/SHARE) are not allowed", t)
+		case t.upper == "LOCK" && next.isWord("IN") && tokenAt(stmt, j+2).isWord("SHARE"):
+			return d.block(blockLockingClause, "LOCK IN SHARE MODE is not allowed", t)
+		case d.name == "sqlserver" && sqlserverLockHints[t.upper] && !qualified:
+			return d.block(blockLockingClause, fmt.Sprintf("locking table hint %s is not allowed", t.upper), t)
+		case t.upper == "NEXT" && next.isWord("VALUE") && tokenAt(stmt, j+2).isWord("FOR"):
+			return d.block(blockSideEffectFunction, "NEXT VALUE FOR advances a sequence", t)
+		case d.unseparatedBatches && sqlserverStatementKeywords[t.upper] && !qualified &&
+			!(t.upper == "FETCH" && prev.isWord("ROW", "ROWS")): // OFFSET n ROWS FETCH NEXT m ROWS ONLY
+			return d.block(blockMultipleStatements,
+				fmt.Sprintf("%s is not allowed anywhere in the statement (SQL Server batches need no separator; quote the identifier if it is a column name)", t.upper), t)
+		case writeKeywords[t.upper] && !qualified && !call && !alias:
+			return d.block(blockDataModifying,
+				fmt.Sprintf("%s is not allowed anywhere in the statement (quote the identifier if it is a column name)", t.upper), t)
+		case call:
+			if err := d.checkCall(t, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkCall refuses a call of function t, whose folded name is t.upper. A
+// quoted name is also checked against the keywords: it may not start a
+// statement, but "insert"(...) or [exec](...) is no harmless call either.
+func (d sqlDialect) checkCall(t sqlToken, quoted bool) error {
+	switch {
+	case sideEffectFunctions[t.upper]:
+		return d.block(blockSideEffectFunction,
+			fmt.Sprintf("function %s has side effects and is not allowed", strings.ToLower(t.upper)), t)
+	case quoted && d.unseparatedBatches && sqlserverStatementKeywords[t.upper]:
+		return d.block(blockMultipleStatements, fmt.Sprintf("%s is not allowed anywhere in the statement", t.upper), t)
+	case quoted && writeKeywords[t.upper]:
+		return d.block(blockDataModifying, fmt.Sprintf("%s is not allowed anywhere in the statement", t.upper), t)
+	}
+	return nil
+}
+
+// unquoteIdent returns the name of a quoted identifier, upper-cased unless the
+// dialect keeps the case of quoted names; there it returns "" for names that
+// are not all lower case, which no built-in function has.
+func (d sqlDialect) unquoteIdent(text string) string {
+	if len(text) < 2 {
+		return ""
+	}
+	closing := text[len(text)-1]
+	name := strings.ReplaceAll(text[1:len(text)-1], string([]byte{closing, closing}), string(closing))
+	if d.caseSensitiveQuoted && name != strings.ToLower(name) {
+		return ""
+	}
+	return strings.ToUpper(name)
+}
+
+// checkExplainTarget skips EXPLAIN options and requires the explained statement
+// to be a query (EXPLAIN ANALYZE executes it).
+func (d sqlDialect) checkExplainTarget(rest []sqlToken) error {
+	i := 0
+	if tokenAt(rest, 0).isPunct("(") && !tokenAt(rest, 1).isWord("SELECT", "WITH", "VALUES", "TABLE") {
+		depth := 0
+		for ; i < len(rest); i++ {
+			if rest[i].isPunct("(") {
+				depth++
+			} else if rest[i].isPunct(")") {
+				depth--
+				if depth == 0 {
+					i++
+					break
+				}
+			}
+		}
+	}
+options:
+	for i < len(rest) {
+		t := rest[i]
+		switch {
+		case t.isWord("ANALYZE", "ANALYSE", "VERBOSE", "EXTENDED", "PARTITIONS"):
+			i++
+		case d.name == "sqlite" && t.isWord("QUERY") && tokenAt(rest, i+1).isWord("PLAN"):
+			i += 2
+		case d.name == "clickhouse" && t.isWord("PLAN", "PIPELINE", "ESTIMATE", "AST", "SYNTAX"):
+			i++
+		case d.name == "clickhouse" && t.kind == tokWord && tokenAt(rest, i+1).isPunct("="):
+			// EXPLAIN PLAN header = 1, actions = 1 SELECT ...
+			i += 3
+			if tokenAt(rest, i).isPunct(",") {
+				i++
+			}
+		case t.isWord("FORMAT"):
+			i++
+			if tokenAt(rest, i).isPunct("=") {
+				i++
+			}
+			i++
+		default:
+			break options
+		}
+	}
+	t := tokenAt(rest, i)
+	switch {
+	case t.isWord("SELECT", "WITH", "VALUES", "TABLE") || t.isPunct("("):
+		return nil
+	case d.name == "mysql" && t.isWord("FOR") && tokenAt(rest, i+1).isWord("CONNECTION"):
+		return nil
+	case d.name == "mysql" && (t.kind == tokQuotedIdent || (t.kind == tokWord && !isStatementKeyword(t.upper))):
+		// EXPLAIN tbl_name is a synonym for DESCRIBE.
+		return nil
+	case i >= len(rest):
+		return d.block(blockExplainTarget, "EXPLAIN requires a statement", t)
+	default:
+		return d.block(blockExplainTarget,
+			fmt.Sprintf("EXPLAIN of %s is not allowed (only queries can be explained)", strings.ToUpper(t.text)), t)
+	}
+}
+
+func isLockingClause(rest []sqlToken) bool {
+	a, b, c := tokenAt(rest, 0), tokenAt(rest, 1), tokenAt(rest, 2)
+	switch {
+	case a.isWord("UPDATE", "SHARE"):
+		return true
+	case a.isWord("NO") && b.isWord("KEY") && c.isWord("UPDATE"):
+		return true
+	case a.isWord("KEY") && b.isWord("SHARE"):
+		return true
+	}
+	return false
+}
+
+var statementKeywords = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true, "REPLACE": true,
+	"CREATE": true, "ALTER": true, "DROP": true, "TRUNCATE": true, "RENAME": true,
+	"GRANT": true, "REVOKE": true, "CALL": true, "DO": true, "SET": true, "LOAD": true,
+	"LOCK": true, "UNLOCK": true, "HANDLER": true, "EXECUTE": true, "PREPARE": true,
+	"ANALYZE": true, "OPTIMIZE": true, "REPAIR": true, "CHECK": true, "FLUSH": true,
+	"KILL": true, "INSTALL": true, "UNINSTALL": true, "IMPORT": true, "COPY": true,
+}
+
+func isStatementKeyword(upper string) bool {
+	return statementKeywords[upper] || writeKeywords[upper]
+}
+
+func tokenAt(tokens []sqlToken, i int) sqlToken {
+	if i < 0 || i >= len(tokens) {
+		return sqlToken{kind: tokPunct, pos: -1}
+	}
+	return tokens[i]
+}
+
+// lexSQL tokenizes q, dropping whitespace and comments.
+func lexSQL(d sqlDialect, q string) ([]sqlToken, error) {
+	var out []sqlToken
+	n := len(q)
+	i := 0
+	for i < n {
+		c := q[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			i++
+
+		case c == '-' && i+1 < n && q[i+1] == '-' && (!d.dashNeedsSpace || i+2 >= n || isSQLSpace(q[i+2])):
+			i = skipLine(q, i)
+
+		case c == '#' && d.hashComments:
+			i = skipLine(q, i)
+
+		case c == '/' && i+1 < n && q[i+1] == '*':
+			if d.executableComments && i+2 < n && (q[i+2] == '!' || (q[i+2] == 'M' && i+3 < n && q[i+3] == '!')) {
+				return nil, d.block(blockExecutableComment, "executable comments (/*! ... */) are not allowed", sqlToken{text: "/*!", pos: i})
+			}
+			end, ok := skipBlockComment(q, i, d.nestedComments)
+			if !ok {
+				return nil, d.block(blockLexError, "unterminated block comment", sqlToken{text: "/*", pos: i})
+			}
+			i = end
+
+		case c == '\'':
+			end, ok := skipQuoted(q, i, '\'', d.backslashEscapes)
+			if !ok {
+				return nil, d.block(blockLexError, "unterminated string literal", sqlToken{text: "'", pos: i})
+			}
+			if err := d.checkEscapedQuote(q[i:end], i); err != nil {
+				return nil, err
+			}
+			out = append(out, sqlToken{kind: tokString, text: q[i:end], pos: i})
+			i = end
+
+		case c == '"':
+			end, ok := skipQuoted(q, i, '"', d.doubleQuoteStrings && d.backslashEscapes)
+			if !ok {
+				return nil, d.block(blockLexError, "unterminated quoted identifier or string", sqlToken{text: `"`, pos: i})
+			}
+			kind := tokQuotedIdent
+			if d.doubleQuoteStrings {
+				kind = tokString
+				if err := d.checkEscapedQuote(q[i:end], i); err != nil {
+					return nil, err
+				}
+			}
+			out = append(out, sqlToken{kind: kind, text: q[i:end], pos: i})
+			i = end
+
+		case c == '`' && d.backtickIdents:
+			end, ok := skipQuoted(q, i, '`', false)
+			if !ok {
+				return nil, d.block(blockLexError, "unterminated quoted identifier", sqlToken{text: "`", pos: i})
+			}
+			out = append(out, sqlToken{kind: tokQuotedIdent, text: q[i:end], pos: i})
+			i = end
+
+		case c == '[' && d.bracketIdents:
+			end, ok := skipQuoted(q, i, ']', false)
+			if !ok {
+				return nil, d.block(blockLexError, "unterminated quoted identifier", sqlToken{text: "[", pos: i})
+			}
+			out = append(out, sqlToken{kind: tokQuotedIdent, text: q[i:end], pos: i})
+			i = end
+
+		case c == '?' && d.numberedQuestions && i+1 < n && isSQLDigit(q[i+1]):
+			j := i + 1
+			for j < n && isSQLDigit(q[j]) {
+				j++
+			}
+			out = append(out, sqlToken{kind: tokParam, text: q[i:j], pos: i})
+			i = j
+
+		case c == '$' && i+1 < n && isSQLDigit(q[i+1]):
+			j := i + 1
+			for j < n && isSQLDigit(q[j]) {
+				j++
+			}
+			out = append(out, sqlToken{kind: tokParam, text: q[i:j], pos: i})
+			i = j
+
+		case c == '$' && d.dollarQuotes:
+			tag, ok := dollarTag(q, i)
+			if !ok {
+				out = append(out, sqlToken{kind: tokPunct, text: "$", pos: i})
+				i++
+				continue
+			}
+			closeAt := strings.Index(q[i+len(tag):], tag)
+			if closeAt < 0 {
+				return nil, d.block(blockLexError, "unterminated dollar-quoted string", sqlToken{text: tag, pos: i})
+			}
+			end := i + len(tag) + closeAt + len(tag)
+			out = append(out, sqlToken{kind: tokString, text: q[i:end], pos: i})
+			i = end
+
+		case isSQLIdentStart(c):
+			j := i + 1
+			for j < n && isSQLIdentPart(q[j]) {
+				j++
+			}
+			word := q[i:j]
+			if d.escapeStrings && (word == "E" || word == "e") && j < n && q[j] == '\'' {
+				end, ok := skipQuoted(q, j, '\'', true)
+				if !ok {
+					return nil, d.block(blockLexError, "unterminated string literal", sqlToken{text: "E'", pos: i})
+				}
+				out = append(out, sqlToken{kind: tokString, text: q[i:end], pos: i})
+				i = end
+				continue
+			}
+			out = append(out, sqlToken{kind: tokWord, text: word, upper: strings.ToUpper(word), pos: i})
+			i = j
+
+		case isSQLDigit(c) || (c == '.' && i+1 < n && isSQLDigit(q[i+1])):
+			j := i + 1
+			for j < n && (isSQLIdentPart(q[j]) || q[j] == '.') {
+				j++
+			}
+			out = append(out, sqlToken{kind: tokNumber, text: q[i:j], pos: i})
+			i = j
+
+		case c == '?':
+			out = append(out, sqlToken{kind: tokParam, text: "?", pos: i})
+			i++
+
+		case c == ':' && i+1 < n && q[i+1] == ':':
+			out = append(out, sqlToken{kind: tokPunct, text: "::", pos: i})
+			i += 2
+
+		case c == ':' && i+1 < n && isSQLIdentStart(q[i+1]):
+			j := i + 2
+			for j < n && isSQLIdentPart(q[j]) && q[j] != '$' {
+				j++
+			}
+			out = append(out, sqlToken{kind: tokParam, text: q[i:j], pos: i})
+			i = j
+
+		default:
+			out = append(out, sqlToken{kind: tokPunct, text: string(c), pos: i})
+			i++
+		}
+	}
+	return out, nil
+}
+
+// checkEscapedQuote refuses a quoted literal (quotes included) that holds a
+// backslash-escaped quote if the dialect's escapes may be switched off.
+func (d sqlDialect) checkEscapedQuote(lit string, pos int) error {
+	if !d.escapesMayBeOff {
+		return nil
+	}
+	quote := lit[0]
+	for j := 1; j < len(lit)-1; j++ {
+		if lit[j] != '\\' {
+			continue
+		}
+		if lit[j+1] == quote {
+			return d.block(blockLexError,
+				"backslash-escaped quotes are ambiguous (the server may run with NO_BACKSLASH_ESCAPES); double the quote instead",
+				sqlToken{text: lit, pos: pos})
+		}
+		j++
+	}
+	return nil
+}
+
+func skipLine(q string, i int) int {
+	nl := strings.IndexByte(q[i:], '\n')
+	if nl < 0 {
+		return len(q)
+	}
+	return i + nl + 1
+}
+
+func skipBlockComment(q string, i int, nested bool) (int, bool) {
+	depth := 0
+	for i < len(q)-1 {
+		switch {
+		case q[i] == '/' && q[i+1] == '*':
+			if depth == 0 || nested {
+				depth++
+			}
+			i += 2
+		case q[i] == '*' && q[i+1] == '/':
+			depth--
+			i += 2
+			if depth == 0 {
+				return i, true
+			}
+		default:
+			i++
+		}
+	}
+	return 0, false
+}
+
+// skipQuoted returns the index just past the closing quote. A doubled quote is
+// always an escaped quote; backslash escapes are honoured when requested.
+func skipQuoted(q string, i int, quote byte, backslash bool) (int, bool) {
+	for j := i + 1; j < len(q); j++ {
+		switch q[j] {
+		case '\\':
+			if backslash {
+				j++
+			}
+		case quote:
+			if j+1 < len(q) && q[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1, true
+		}
+	}
+	return 0, false
+}
+
+// dollarTag returns the opening $tag$ at q[i], if any.
+func dollarTag(q string, i int) (string, bool) {
+	j := i + 1
+	if j < len(q) && q[j] == '$' {
+		return "$$", true
+	}
+	if j >= len(q) || !(isSQLIdentStart(q[j])) {
+		return "", false
+	}
+	for j < len(q) && isSQLIdentPart(q[j]) && q[j] != '$' {
+		j++
+	}
+	if j < len(q) && q[j] == '$' {
+		return q[i : j+1], true
+	}
+	return "", false
+}
+
+func isSQLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isSQLDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isSQLIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isSQLIdentPart(c byte) bool {
+	return isSQLIdentStart(c) || isSQLDigit(c) || c == '$'
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// guardCase is a query and the block code checkReadOnlySQL must return for
+// it, "" when the query is allowed.
+type guardCase struct {
+	kind  DriverKind
+	query string
+	code  string
+}
+
+var guardCorpus = []guardCase{
+	// Allowed queries, pinned against false positives.
+	{DriverPostgres, `SELECT 1`, ""},
+	{DriverPostgres, `select * from t where id = $1`, ""},
+	{DriverPostgres, `WITH x AS (SELECT 1) SELECT * FROM x;`, ""},
+	{DriverPostgres, `VALUES (1), (2)`, ""},
+	{DriverPostgres, `TABLE pg_class`, ""},
+	{DriverPostgres, `SHOW search_path`, ""},
+	{DriverPostgres, `(SELECT 1) UNION (SELECT 2)`, ""},
+	{DriverPostgres, `SELECT 'DROP TABLE t; DELETE FROM t'`, ""},
+	{DriverPostgres, `SELECT $$; DELETE FROM t; $$`, ""},
+	{DriverPostgres, `SELECT $tag$ it's ; INSERT $tag$`, ""},
+	{DriverPostgres, `SELECT E'\'; DELETE FROM t; --'`, ""},
+	{DriverPostgres, `SELECT 1 -- ; DELETE FROM t`, ""},
+	{DriverPostgres, `SELECT /* /* nested */ ; DELETE */ 1`, ""},
+	{DriverPostgres, `SELECT "update", "insert" FROM t`, ""},
+	{DriverPostgres, `SELECT t.update FROM t`, ""},
+	{DriverPostgres, `SELECT data ? 'key' FROM t`, ""},
+	{DriverPostgres, `SELECT 1 FROM t FOR READ ONLY`, ""},
+	{DriverPostgres, `SELECT now()::date`, ""},
+	{DriverPostgres, `SELECT pg_sleep(0)`, ""},
+	{DriverPostgres, `SELECT "PG_READ_FILE"('x')`, ""}, // quoted names keep their case: no such function
+	{DriverPostgres, `SELECT "lower"('A'), "replace"('a', 'a', 'b')`, ""},
+	{DriverPostgres, `SELECT "pg_read_file" FROM t`, ""},
+	{DriverPostgres, `EXPLAIN SELECT 1`, ""},
+	{DriverPostgres, `EXPLAIN (FORMAT JSON) SELECT 1`, ""},
+	{DriverPostgres, `EXPLAIN ANALYZE SELECT 1`, ""},
+	{DriverMySQL, `SELECT * FROM t LIMIT 10`, ""},
+	{DriverMySQL, "SELECT `delete` FROM t", ""},
+	{DriverMySQL, `SELECT "it's; DROP" FROM t`, ""},
+	{DriverMySQL, `SELECT 'it''s', 'a\\', 'tab\t'`, ""},
+	{DriverMySQL, `SELECT 1 AS update, 2 AS ` + "`delete`", ""},
+	{DriverPostgres, `SELECT 1 AS update, t.x AS insert FROM t`, ""},
+	{DriverMySQL, `SELECT 1 -- ; DROP TABLE t`, ""},
+	{DriverMySQL, "SELECT 1 # ; DROP TABLE t\n", ""},
+	{DriverMySQL, `SHOW TABLES`, ""},
+	{DriverMySQL, `DESCRIBE t`, ""},
+	{DriverMySQL, `EXPLAIN t`, ""},
+	{DriverMySQL, `EXPLAIN FOR CONNECTION 5`, ""},
+	{DriverMySQL, `EXPLAIN FORMAT=JSON SELECT 1`, ""},
+	{DriverSQLite, `SELECT * FROM [order]`, ""},
+	{DriverSQLite, `SELECT ?1, ?2`, ""},
+	{DriverSQLite, `EXPLAIN QUERY PLAN SELECT 1`, ""},
+	{DriverSQLServer, `SELECT TOP 10 * FROM dbo.t`, ""},
+	{DriverSQLServer, `SELECT * FROM t ORDER BY id OFFSET 10 ROWS FETCH NEXT 5 ROWS ONLY`, ""},
+	{DriverSQLServer, `SELECT * FROM t ORDER BY id OFFSET 0 ROW FETCH FIRST 1 ROW ONLY`, ""},
+	{DriverSQLServer, `SELECT [set], [exec] FROM t`, ""},
+	{DriverSQLServer, `SELECT * FROM t WITH (NOLOCK)`, ""},
+	{DriverSQLServer, `SELECT x.drop FROM t x`, ""},
+	{DriverClickHouse, `SELECT count() FROM system.tables`, ""},
+	{DriverClickHouse, `SELECT * FROM numbers(10)`, ""},
+	{DriverClickHouse, `DESCRIBE TABLE t`, ""},
+	{DriverClickHouse, `EXPLAIN PLAN header = 1, actions = 1 SELECT 1`, ""},
+	{DriverClickHouse, `EXPLAIN SYNTAX SELECT 1`, ""},
+	{DriverClickHouse, `SELECT 'a\'; DROP TABLE t'`, ""},
+	{DriverDuckDB, `FROM t`, ""},
+	{DriverDuckDB, `FROM t SELECT a`, ""},
+	{DriverDuckDB, `SUMMARIZE t`, ""},
+	{DriverDuckDB, `DESCRIBE t`, ""},
+	{DriverDuckDB, `SELECT * FROM read_parquet('data/*.parquet')`, ""},
+
+	// empty_query
+	{DriverPostgres, ``, blockEmpty},
+	{DriverPostgres, ` ; ; `, blockEmpty},
+	{DriverPostgres, `-- only a comment`, blockEmpty},
+	{DriverMySQL, `/* nothing */`, blockEmpty},
+	{DriverSQLServer, `;`, blockEmpty},
+
+	// lex_error
+	{DriverPostgres, `SELECT 'unterminated`, blockLexError},
+	{DriverPostgres, `SELECT $$ unterminated`, blockLexError},
+	{DriverPostgres, `SELECT /* /* */ 1`, blockLexError},
+	{DriverMySQL, `SELECT 'a\'`, blockLexError},
+	// With NO_BACKSLASH_ESCAPES the string ends at \' and DROP would run.
+	{DriverMySQL, `SELECT 'a\'; DROP TABLE t; -- '`, blockLexError},
+	{DriverMySQL, `SELECT "a\"; DROP TABLE t; -- "`, blockLexError},
+	{DriverMySQL, `SELECT 'a\\\'; DROP TABLE t; -- '`, blockLexError},
+	{DriverMySQL, "SELECT `x", blockLexError},
+	{DriverSQLite, `SELECT [x`, blockLexError},
+	{DriverSQLServer, `SELECT "x`, blockLexError},
+	{DriverClickHouse, `SELECT 'x\'`, blockLexError},
+	{DriverDuckDB, `SELECT E'\'`, blockLexError},
+
+	// executable_comment
+	{DriverMySQL, `SELECT 1 /*! ; DROP TABLE t */`, blockExecutableComment},
+	{DriverMySQL, `SELECT /*!50000 1 */`, blockExecutableComment},
+	{DriverMySQL, `SELECT /*M! 1 */`, blockExecutableComment},
+
+	// multiple_statements
+	{DriverPostgres, `SELECT 1; DELETE FROM t`, blockMultipleStatements},
+	{DriverPostgres, `SELECT 1; SELECT 2`, blockMultipleStatements},
+	{DriverPostgres, `SELECT $$x$$; DROP TABLE t`, blockMultipleStatements},
+	{DriverMySQL, `SELECT "a""b"; DROP TABLE t`, blockMultipleStatements},
+	{DriverMySQL, `SELECT 1 --x; DROP TABLE t`, blockMultipleStatements},
+	{DriverSQLite, `SELECT 1; ATTACH 'x.db' AS x`, blockMultipleStatements},
+	{DriverSQLServer, `SELECT 1 DROP TABLE t`, blockMultipleStatements},
+	{DriverSQLServer, `SELECT 1 EXEC sp_who`, blockMultipleStatements},
+	{DriverSQLServer, `SELECT 1 EXEC('DROP TABLE t')`, blockMultipleStatements},
+	{DriverSQLServer, `SELECT 1 EXECUTE('DROP TABLE t')`, blockMultipleStatements},
+	{DriverSQLServer, `SELECT 1 exec (N'DROP TABLE t')`, blockMultipleStatements},
+	{DriverSQLServer, `SELECT (1) EXEC('sp_configure')`, blockMultipleStatements},
+	{DriverSQLServer, `SELECT 1 IF(1=1) DROP TABLE t`, blockMultipleStatements},
+	{DriverSQLServer, `SELECT 1 WHILE(1=1) BREAK`, blockMultipleStatements},
+	{DriverSQLServer, `SELECT 1 SHUTDOWN`, blockMultipleStatements},
+	{DriverSQLServer, `SELECT 1 WAITFOR DELAY '00:00:10'`, blockMultipleStatements},
+	{DriverSQLServer, `SELECT 1 DECLARE @x int SET @x = 1`, blockMultipleStatements},
+	{DriverClickHouse, `SELECT 1; DROP TABLE t`, blockMultipleStatements},
+	{DriverDuckDB, `SELECT 1; COPY t TO 'x.csv'`, blockMultipleStatements},
+
+	// statement_not_allowed
+	{DriverPostgres, `DELETE FROM t`, blockStatementType},
+	{DriverPostgres, `  (  INSERT INTO t VALUES (1))`, blockStatementType},
+	{DriverPostgres, `COPY t TO '/tmp/x'`, blockStatementType},
+	{DriverPostgres, `SET ROLE admin`, blockStatementType},
+	{DriverPostgres, `CALL proc()`, blockStatementType},
+	{DriverPostgres, `DO $$ BEGIN END $$`, blockStatementType},
+	{DriverPostgres, `DESCRIBE t`, blockStatementType},
+	{DriverPostgres, `((`, blockStatementType},
+	{DriverMySQL, `REPLACE INTO t VALUES (1)`, blockStatementType},
+	{DriverMySQL, `HANDLER t OPEN`, blockStatementType},
+	{DriverMySQL, `LOAD DATA INFILE 'x' INTO TABLE t`, blockStatementType},
+	{DriverSQLite, `PRAGMA writable_schema = 1`, blockStatementType},
+	{DriverSQLite, `ATTACH 'x.db' AS x`, blockStatementType},
+	{DriverSQLite, `VACUUM INTO '/tmp/x.db'`, blockStatementType},
+	{DriverSQLServer, `EXEC sp_configure`, blockStatementType},
+	{DriverSQLServer, `UPDATE t SET a = 1`, blockStatementType},
+	{DriverClickHouse, `OPTIMIZE TABLE t FINAL`, blockStatementType},
+	{DriverClickHouse, `SYSTEM SHUTDOWN`, blockStatementType},
+	{DriverDuckDB, `ATTACH 'x.db'`, blockStatementType},
+	{DriverDuckDB, `INSTALL httpfs`, blockStatementType},
+
+	// explain_target_not_allowed
+	{DriverPostgres, `EXPLAIN ANALYZE DELETE FROM t`, blockExplainTarget},
+	{DriverPostgres, `EXPLAIN (ANALYZE) INSERT INTO t VALUES (1)`, blockExplainTarget},
+	{DriverPostgres, `EXPLAIN`, blockExplainTarget},
+	{DriverMySQL, `EXPLAIN ANALYZE UPDATE t SET a = 1`, blockExplainTarget},
+	{DriverMySQL, `EXPLAIN DELETE FROM t`, blockExplainTarget},
+	{DriverSQLite, `EXPLAIN QUERY PLAN DELETE FROM t`, blockExplainTarget},
+	{DriverClickHouse, `EXPLAIN PIPELINE INSERT INTO t SELECT 1`, blockExplainTarget},
+	{DriverDuckDB, `EXPLAIN ANALYZE COPY t TO 'x'`, blockExplainTarget},
+
+	// data_modifying_statement
+	{DriverPostgres, `WITH d AS (DELETE FROM t RETURNING *) SELECT * FROM d`, blockDataModifying},
+	{DriverPostgres, `WITH u AS (UPDATE t SET a = 1 RETURNING a) SELECT 1`, blockDataModifying},
+	{DriverPostgres, `WITH m AS (MERGE INTO t USING s ON true WHEN MATCHED THEN DELETE) SELECT 1`, blockDataModifying},
+	{DriverMySQL, `SELECT update FROM t`, blockDataModifying},
+	{DriverPostgres, `SELECT 1 AS x UPDATE`, blockDataModifying},
+	{DriverSQLite, `WITH x AS (SELECT 1) INSERT INTO t SELECT * FROM x`, blockDataModifying},
+	{DriverSQLServer, `WITH x AS (SELECT 1 a) DELETE FROM t`, blockDataModifying},
+	{DriverClickHouse, `WITH 1 AS x INSERT INTO t SELECT x`, blockDataModifying},
+	{DriverDuckDB, `WITH x AS (SELECT 1) DELETE FROM t`, blockDataModifying},
+
+	// select_into
+	{DriverPostgres, `SELECT * INTO new_t FROM t`, blockSelectInto},
+	{DriverMySQL, `SELECT * FROM t INTO OUTFILE '/tmp/x'`, blockSelectInto},
+	{DriverMySQL, `SELECT * FROM t INTO DUMPFILE '/tmp/x'`, blockSelectInto},
+	{DriverMySQL, `SELECT 1 INTO @v`, blockSelectInto},
+	{DriverSQLServer, `SELECT * INTO #tmp FROM t`, blockSelectInto},
+	{DriverClickHouse, `SELECT * FROM t INTO OUTFILE 'x.csv'`, blockSelectInto},
+	{DriverDuckDB, `SELECT 1 INTO x`, blockSelectInto},
+
+	// locking_clause
+	{DriverPostgres, `SELECT * FROM t FOR UPDATE`, blockLockingClause},
+	{DriverPostgres, `SELECT * FROM t FOR NO KEY UPDATE`, blockLockingClause},
+	{DriverPostgres, `SELECT * FROM t FOR SHARE SKIP LOCKED`, blockLockingClause},
+	{DriverPostgres, `SELECT * FROM t FOR KEY SHARE`, blockLockingClause},
+	{DriverMySQL, `SELECT * FROM t FOR UPDATE NOWAIT`, blockLockingClause},
+	{DriverMySQL, `SELECT * FROM t LOCK IN SHARE MODE`, blockLockingClause},
+	{DriverSQLServer, `SELECT * FROM t WITH (UPDLOCK)`, blockLockingClause},
+	{DriverSQLServer, `SELECT * FROM t WITH (XLOCK, ROWLOCK)`, blockLockingClause},
+	{DriverSQLServer, `SELECT * FROM t (TABLOCKX)`, blockLockingClause},
+	{DriverSQLServer, `SELECT * FROM t WITH (HOLDLOCK)`, blockLockingClause},
+
+	// side_effect_function
+	{DriverPostgres, `SELECT pg_terminate_backend(pid) FROM pg_stat_activity`, blockSideEffectFunction},
+	{DriverPostgres, `SELECT pg_catalog.pg_read_file('/etc/passwd')`, blockSideEffectFunction},
+	{DriverPostgres, `SELECT PG_RELOAD_CONF()`, blockSideEffectFunction},
+	{DriverPostgres, `SELECT set_config('role', 'admin', false)`, blockSideEffectFunction},
+	{DriverPostgres, `SELECT nextval('s')`, blockSideEffectFunction},
+	{DriverPostgres, `SELECT lo_import('/etc/passwd')`, blockSideEffectFunction},
+	{DriverPostgres, `SELECT * FROM dblink('host=x', 'DELETE FROM t') AS r(x int)`, blockSideEffectFunction},
+	{DriverPostgres, `SELECT query_to_xml('DELETE FROM t', true, false, '')`, blockSideEffectFunction},
+	{DriverPostgres, `SELECT pg_advisory_lock (1)`, blockSideEffectFunction},
+	{DriverMySQL, `SELECT SLEEP(10)`, blockSideEffectFunction},
+	{DriverMySQL, `SELECT BENCHMARK(1000000, MD5('x'))`, blockSideEffectFunction},
+	{DriverMySQL, `SELECT LOAD_FILE('/etc/passwd')`, blockSideEffectFunction},
+	{DriverMySQL, `SELECT GET_LOCK('x', 10)`, blockSideEffectFunction},
+	{DriverSQLite, `SELECT load_extension('evil.so')`, blockSideEffectFunction},
+	{DriverSQLite, `SELECT writefile('/tmp/x', 'y')`, blockSideEffectFunction},
+	{DriverSQLServer, `SELECT * FROM OPENROWSET('SQLNCLI', 'x', 'SELECT 1')`, blockSideEffectFunction},
+	{DriverSQLServer, `SELECT * FROM OPENQUERY(srv, 'SELECT 1')`, blockSideEffectFunction},
+	{DriverSQLServer, `SELECT NEXT VALUE FOR dbo.seq`, blockSideEffectFunction},
+	{DriverClickHouse, `SELECT * FROM file('/etc/passwd', 'LineAsString')`, blockSideEffectFunction},
+	{DriverClickHouse, `SELECT * FROM url('http://x', CSV)`, blockSideEffectFunction},
+	{DriverClickHouse, `SELECT * FROM s3('https://b/x.csv')`, blockSideEffectFunction},
+	{DriverClickHouse, `SELECT * FROM executable('x.sh', CSV, 'a int')`, blockSideEffectFunction},
+	{DriverClickHouse, `SELECT * FROM remote('h', db.t)`, blockSideEffectFunction},
+	{DriverClickHouse, `SELECT * FROM mysql('h:3306', 'db', 't', 'u', 'p')`, blockSideEffectFunction},
+	{DriverClickHouse, `SELECT * FROM postgresql('h', 'db', 't', 'u', 'p')`, blockSideEffectFunction},
+	{DriverClickHouse, `SELECT * FROM hdfs('hdfs://x', CSV)`, blockSideEffectFunction},
+	{DriverClickHouse, `SELECT * FROM sqlite('/etc/passwd', 't')`, blockSideEffectFunction},
+	{DriverClickHouse, `SELECT * FROM fileCluster('c', '/etc/passwd')`, blockSideEffectFunction},
+	{DriverClickHouse, `SELECT * FROM hdfsCluster('c', 'hdfs://x', CSV)`, blockSideEffectFunction},
+	{DriverClickHouse, `SELECT * FROM deltaLake('https://b/t')`, blockSideEffectFunction},
+	{DriverClickHouse, `SELECT * FROM iceberg('https://b/t')`, blockSideEffectFunction},
+	{DriverClickHouse, `SELECT * FROM icebergS3('https://b/t')`, blockSideEffectFunction},
+	{DriverClickHouse, `SELECT * FROM azureBlobStorageCluster('c', 'x')`, blockSideEffectFunction},
+	{DriverClickHouse, `SELECT * FROM urlCluster('c', 'http://x')`, blockSideEffectFunction},
+	{DriverClickHouse, `SELECT * FROM input('a int')`, blockSideEffectFunction},
+	{DriverDuckDB, `SELECT * FROM query('DELETE FROM t')`, blockSideEffectFunction},
+	// Quoted function names.
+	{DriverPostgres, `SELECT "pg_terminate_backend"(pid) FROM pg_stat_activity`, blockSideEffectFunction},
+	{DriverPostgres, `SELECT pg_catalog."pg_read_file"('/etc/passwd')`, blockSideEffectFunction},
+	{DriverPostgres, `SELECT "dblink_exec"('host=x', 'DROP TABLE t')`, blockSideEffectFunction},
+	{DriverPostgres, `SELECT "insert"(1)`, blockDataModifying},
+	{DriverMySQL, "SELECT `sleep`(10)", blockSideEffectFunction},
+	{DriverMySQL, "SELECT `LOAD_FILE`('/etc/passwd')", blockSideEffectFunction},
+	{DriverSQLite, `SELECT "load_extension"('evil.so')`, blockSideEffectFunction},
+	{DriverSQLite, `SELECT [writefile]('/tmp/x', 'y')`, blockSideEffectFunction},
+	{DriverSQLServer, `SELECT * FROM [OPENROWSET]('SQLNCLI', 'x', 'SELECT 1')`, blockSideEffectFunction},
+	{DriverSQLServer, `SELECT 1 [EXEC]('DROP TABLE t')`, blockMultipleStatements},
+	{DriverClickHouse, "SELECT * FROM `file`('/etc/passwd', 'LineAsString')", blockSideEffectFunction},
+	{DriverClickHouse, `SELECT * FROM "url"('http://x', CSV)`, blockSideEffectFunction},
+	{DriverDuckDB, `SELECT * FROM "Query"('DELETE FROM t')`, blockSideEffectFunction},
+}
+
+func TestCheckReadOnlySQL(t *testing.T) {
+	for _, tc := range guardCorpus {
+		err := checkReadOnlySQL(tc.kind, tc.query)
+		var blocked *sqlBlockError
+		switch {
+		case tc.code == "" && err != nil:
+			t.Errorf("%s %q: blocked (%v), want allowed", tc.kind, tc.query, err)
+		case tc.code == "":
+		case !errors.As(err, &blocked):
+			t.Errorf("%s %q: allowed (err %v), want %s", tc.kind, tc.query, err, tc.code)
+		case blocked.Code != tc.code:
+			t.Errorf("%s %q: blocked as %s (%s), want %s", tc.kind, tc.query, blocked.Code, blocked.Reason, tc.code)
+		case blocked.Dialect != string(tc.kind):
+			t.Errorf("%s %q: reported dialect %s", tc.kind, tc.query, blocked.Dialect)
+		}
+	}
+}
+
+func TestTrimStatement(t *testing.T) {
+	for _, tc := range []struct {
+		kind        DriverKind
+		query, want string
+	}{
+		{DriverPostgres, `SELECT 1;`, `SELECT 1`},
+		{DriverPostgres, `SELECT 1 ; -- done`, `SELECT 1`},
+		{DriverPostgres, `SELECT ';'`, `SELECT ';'`},
+		{DriverMySQL, "SELECT 1;;\n", `SELECT 1`},
+	} {
+		if got := trimStatement(tc.kind, tc.query); got != tc.want {
+			t.Errorf("trimStatement(%s, %q) = %q, want %q", tc.kind, tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestLeadingKeyword(t *testing.T) {
+	for _, tc := range []struct {
+		kind        DriverKind
+		query, want string
+	}{
+		{DriverPostgres, `/* c */ ((select 1))`, "SELECT"},
+		{DriverPostgres, `-- x` + "\n" + `with x as (select 1) table x`, "WITH"},
+		{DriverMySQL, `'unterminated`, ""},
+	} {
+		if got := leadingKeyword(tc.kind, tc.query); got != tc.want {
+			t.Errorf("leadingKeyword(%s, %q) = %q, want %q", tc.kind, tc.query, got, tc.want)
+		}
+	}
+}
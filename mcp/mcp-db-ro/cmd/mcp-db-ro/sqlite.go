@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+type sqliteDriver struct{}
+
+func init() {
+	registerDriver(driverSpec{
+		kind:          DriverSQLite,
+		aliases:       []string{"sqlite3"},
+		sqlDriverName: "sqlite",
+		newDriver:     func() DBDriver { return sqliteDriver{} },
+		dsn:           sqliteDSN,
+		// "Databases" are the schemas of the connection (main, temp and any
+		// attached files); there is no separate schema level.
+		scope:         scopeRules{fallbackDatabase: "main", noSchema: true},
+		checkDatabase: checkDatabaseQuery(`SELECT name FROM pragma_database_list WHERE name = ?`, "database not attached"),
+		dialect:       sqliteDialect,
+		placeholders:  placeholderNumberedQuestion,
+	})
+}
+
+func (sqliteDriver) Kind() DriverKind { return DriverSQLite }
+
+// ReadOnlyConnection implements readOnlyConnector: files are opened with
+// mode=ro and query_only (see sqliteDSN). The modernc driver ignores
+// TxOptions.ReadOnly, so a transaction would add nothing.
+func (sqliteDriver) ReadOnlyConnection() bool { return true }
+
+// ListDatabases lists the schemas of the connection: main, temp and any
+// attached database files.
+func (sqliteDriver) ListDatabases(ctx context.Context, db *sql.DB) ([]map[string]any, error) {
+	return queryAll(ctx, db, `SELECT name, file FROM pragma_database_list ORDER BY seq`)
+}
+
+func (sqliteDriver) ListSchemas(ctx context.Context, db *sql.DB) ([]map[string]any, error) {
+	return []map[string]any{}, nil
+}
+
+func (sqliteDriver) ListTables(ctx context.Context, db *sql.DB, scope TableScope) ([]map[string]any, error) {
+	return queryAll(ctx, db, `
+SELECT name AS table_name
+FROM `+quoteSQLiteIdent(scope.Database)+`.sqlite_master
+WHERE type = 'table' AND name NOT LIKE 'sqlite\_%' ESCAPE '\'
+ORDER BY name`)
+}
+
+func (sqliteDriver) DescribeTable(ctx context.Context, db *sql.DB, ref TableRef) ([]map[string]any, error) {
+	return queryAll(ctx, db, `
+SELECT
+  cid + 1 AS ordinal_position,
+  name AS column_name,
+  type AS data_type,
+  CASE WHEN "notnull" THEN 'NO' ELSE 'YES' END AS is_nullable,
+  dflt_value AS column_default,
+  pk
+FROM pragma_table_info(?, ?)
+ORDER BY cid`, ref.Table, ref.Database)
+}
+
+func (sqliteDriver) ListIndexes(ctx context.Context, db *sql.DB, ref TableRef) ([]map[string]any, error) {
+	return queryAll(ctx, db, `
+SELECT
+  il.name AS index_name,
+  il."unique" AS is_unique,
+  il.origin,
+  il.partial,
+  ii.seqno + 1 AS seq_in_index,
+  ii.name AS column_name
+FROM pragma_index_list(?, ?) AS il
+LEFT JOIN pragma_index_info(il.name, ?) AS ii
+ORDER BY il.name, ii.seqno`, ref.Table, ref.Database, ref.Database)
+}
+
+// TablePartitions returns nothing: SQLite has no table partitioning.
+func (sqliteDriver) TablePartitions(ctx context.Context, db *sql.DB, ref TableRef) ([]map[string]any, error) {
+	return []map[string]any{}, nil
+}
+
+func (sqliteDriver) Explain(ctx context.Context, db queryer, query, _ string, args ...any) ([]map[string]any, error) {
+	return queryAll(ctx, db, "EXPLAIN QUERY PLAN "+query, args...)
+}
+
+// ColumnEncoder implements valueEncoder. SQLite is dynamically typed, so the
+// conversion mostly follows the stored value: blobs come back as []byte and
+// columns declared DATE/DATETIME/TIMESTAMP as time.Time.
+func (sqliteDriver) ColumnEncoder(col resultColumn) func(any) any {
+	typ := col.DatabaseType
+	switch {
+	case typ == "JSON":
+		return encodeJSON
+	case typ == "DATE":
+		return encodeSQLiteValue(encodeDate)
+	case strings.Contains(typ, "BLOB"):
+		return binaryEncoder("blob")
+	}
+	return encodeSQLiteValue(encodeTimestamp)
+}
+
+func encodeSQLiteValue(encodeTime func(any) any) func(any) any {
+	blob := binaryEncoder("blob")
+	return func(v any) any {
+		switch v.(type) {
+		case []byte:
+			return blob(v)
+		case float64:
+			return encodeFloat(v)
+		}
+		return encodeTime(v)
+	}
+}
+
+func quoteSQLiteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
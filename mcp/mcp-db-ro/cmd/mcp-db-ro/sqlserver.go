@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+type sqlserverDriver struct{}
+
+func init() {
+	registerDriver(driverSpec{
+		kind:            DriverSQLServer,
+		aliases:         []string{"mssql"},
+		sqlDriverName:   "sqlserver",
+		newDriver:       func() DBDriver { return sqlserverDriver{} },
+		dsn:             sqlserverDSN,
+		scope:           scopeRules{defaultSchema: "dbo"},
+		poolPerDatabase: true,
+		checkDatabase:   checkDatabasePool,
+		dialect:         sqlserverDialect,
+		placeholders:    placeholderAtP,
+	})
+}
+
+func (sqlserverDriver) Kind() DriverKind { return DriverSQLServer }
+
+// sqlserverObjectID resolves @p1.@p2 to the table's object_id. Catalog views
+// only cover the current database, which is why each database gets its own
+// pool (see dbForDatabase).
+const sqlserverObjectID = `OBJECT_ID(QUOTENAME(@p1) + '.' + QUOTENAME(@p2), 'U')`
+
+// sqlserverTypeExpr renders a column's type as it would appear in CREATE
+// TABLE. It expects sys.columns as c and sys.types as ty.
+const sqlserverTypeExpr = `
+  CASE
+    WHEN ty.name IN ('varchar', 'char', 'varbinary', 'binary')
+      THEN ty.name + '(' + CASE WHEN c.max_length = -1 THEN 'max' ELSE CAST(c.max_length AS varchar(10)) END + ')'
+    WHEN ty.name IN ('nvarchar', 'nchar')
+      THEN ty.name + '(' + CASE WHEN c.max_length = -1 THEN 'max' ELSE CAST(c.max_length / 2 AS varchar(10)) END + ')'
+    WHEN ty.name IN ('decimal', 'numeric')
+      THEN ty.name + '(' + CAST(c.precision AS varchar(10)) + ',' + CAST(c.scale AS varchar(10)) + ')'
+    WHEN ty.name IN ('datetime2', 'time', 'datetimeoffset')
+      THEN ty.name + '(' + CAST(c.scale AS varchar(10)) + ')'
+    ELSE ty.name
+  END`
+
+func (sqlserverDriver) ListDatabases(ctx context.Context, db *sql.DB) ([]map[string]any, error) {
+	return queryAll(ctx, db, `SELECT name, state_desc FROM sys.databases ORDER BY name`)
+}
+
+func (sqlserverDriver) ListSchemas(ctx context.Context, db *sql.DB) ([]map[string]any, error) {
+	return queryAll(ctx, db, `SELECT name AS schema_name FROM sys.schemas ORDER BY name`)
+}
+
+func (sqlserverDriver) ListTables(ctx context.Context, db *sql.DB, scope TableScope) ([]map[string]any, error) {
+	return queryAll(ctx, db, `
+SELECT t.name AS table_name
+FROM sys.tables t
+JOIN sys.schemas s ON s.schema_id = t.schema_id
+WHERE s.name = @p1 AND t.is_ms_shipped = 0
+ORDER BY t.name`, scope.Schema)
+}
+
+func (sqlserverDriver) DescribeTable(ctx context.Context, db *sql.DB, ref TableRef) ([]map[string]any, error) {
+	return queryAll(ctx, db, `
+SELECT
+  c.column_id AS ordinal_position,
+  c.name AS column_name,`+sqlserverTypeExpr+` AS data_type,
+  CASE WHEN c.is_nullable = 1 THEN 'YES' ELSE 'NO' END AS is_nullable,
+  dc.definition AS column_default,
+  c.is_identity,
+  c.is_computed
+FROM sys.columns c
+JOIN sys.types ty ON ty.user_type_id = c.user_type_id
+LEFT JOIN sys.default_constraints dc ON dc.object_id = c.default_object_id
+WHERE c.object_id = `+sqlserverObjectID+`
+ORDER BY c.column_id`, ref.Schema, ref.Table)
+}
+
+func (sqlserverDriver) ListIndexes(ctx context.Context, db *sql.DB, ref TableRef) ([]map[string]any, error) {
+	return queryAll(ctx, db, `
+SELECT
+  i.name AS index_name,
+  i.type_desc AS index_type,
+  i.is_unique,
+  i.is_primary_key,
+  ic.key_ordinal AS seq_in_index,
+  c.name AS column_name,
+  ic.is_descending_key,
+  ic.is_included_column,
+  i.filter_definition
+FROM sys.indexes i
+JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+WHERE i.object_id = `+sqlserverObjectID+` AND i.type > 0
+ORDER BY i.name, ic.is_included_column, ic.key_ordinal, ic.index_column_id`, ref.Schema, ref.Table)
+}
+
+// TablePartitions lists the partitions of the heap or clustered index with
+// their bounds; tables not on a partition scheme return no rows.
+func (sqlserverDriver) TablePartitions(ctx context.Context, db *sql.DB, ref TableRef) ([]map[string]any, error) {
+	return queryAll(ctx, db, `
+SELECT
+  p.partition_number,
+  ps.name AS partition_scheme,
+  pf.name AS partition_function,
+  CASE WHEN pf.boundary_value_on_right = 1 THEN 'RIGHT' ELSE 'LEFT' END AS range_type,
+  lo.value AS lower_boundary,
+  hi.value AS upper_boundary,
+  p.rows,
+  p.data_compression_desc
+FROM sys.partitions p
+JOIN sys.indexes i ON i.object_id = p.object_id AND i.index_id = p.index_id
+JOIN sys.partition_schemes ps ON ps.data_space_id = i.data_space_id
+JOIN sys.partition_functions pf ON pf.function_id = ps.function_id
+LEFT JOIN sys.partition_range_values lo ON lo.function_id = pf.function_id AND lo.boundary_id = p.partition_number - 1
+LEFT JOIN sys.partition_range_values hi ON hi.function_id = pf.function_id AND hi.boundary_id = p.partition_number
+WHERE p.object_id = `+sqlserverObjectID+` AND p.index_id IN (0, 1)
+ORDER BY p.partition_number`, ref.Schema, ref.Table)
+}
+
+// Explain returns the estimated plan as showplan XML. With SHOWPLAN_XML on the
+// server compiles but does not execute statements; the option is per session,
+// so it is always switched off again before the connection is reused.
+func (sqlserverDriver) Explain(ctx context.Context, db queryer, query, format string, args ...any) (plan []map[string]any, err error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "xml":
+	default:
+		return nil, fmt.Errorf("unsupported format: %s (sqlserver supports: xml)", format)
+	}
+	if _, err := db.ExecContext(ctx, `SET SHOWPLAN_XML ON`); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if _, offErr := db.ExecContext(context.WithoutCancel(ctx), `SET SHOWPLAN_XML OFF`); offErr != nil && err == nil {
+			err = offErr
+		}
+	}()
+	rs, err := queryAll(ctx, db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	// The single column is named "Microsoft SQL Server 2005 XML Showplan".
+	plan = make([]map[string]any, 0, len(rs))
+	for _, row := range rs {
+		for _, v := range row {
+			plan = append(plan, map[string]any{"showplan_xml": v})
+		}
+	}
+	return plan, nil
+}
+
+// PageQuery wraps a query in a derived table paged with OFFSET/FETCH. Queries
+// with their own ORDER BY (or a CTE) cannot be wrapped and are paged
+// client-side instead.
+func (sqlserverDriver) PageQuery(query string, limit, offset int) (string, bool) {
+	if leadingKeyword(DriverSQLServer, query) != "SELECT" {
+		return "", false
+	}
+	return fmt.Sprintf("SELECT * FROM (\n%s\n) AS mcp_page ORDER BY (SELECT NULL) OFFSET %d ROWS FETCH NEXT %d ROWS ONLY", query, offset, limit), true
+}
+
+// IsPageRewriteError matches the errors raised by wrapping a query in a
+// derived table: ORDER BY without TOP/OFFSET (1033), unnamed columns (8155)
+// and duplicate column names (8156).
+func (sqlserverDriver) IsPageRewriteError(err error) bool {
+	var me mssql.Error
+	if !errors.As(err, &me) {
+		return false
+	}
+	switch me.Number {
+	case 1033, 8155, 8156:
+		return true
+	}
+	return false
+}
+
+func quoteIdentSQLServer(s string) string {
+	return "[" + strings.ReplaceAll(s, "]", "]]") + "]"
+}
@@ -3,87 +3,146 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"math"
 	"strings"
-	"time"
 )
 
-func isReadOnlySQL(q string) bool {
-	s := strings.ToLower(strings.TrimSpace(q))
-	if s == "" {
-		return false
-	}
-	for strings.HasPrefix(s, "--") {
-		nl := strings.Index(s, "\n")
-		if nl == -1 {
-			return false
-		}
-		s = strings.TrimSpace(s[nl+1:])
-	}
-	for strings.HasPrefix(s, "/*") {
-		end := strings.Index(s, "*/")
-		if end == -1 {
-			return false
+// queryer is satisfied by *sql.DB, *sql.Conn and *sql.Tx.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// resultColumn describes one result column. Fields the driver cannot report
+// are omitted.
+type resultColumn struct {
+	Name         string `json:"name"`
+	DatabaseType string `json:"databaseType,omitempty"`
+	Nullable     *bool  `json:"nullable,omitempty"`
+	Precision    *int64 `json:"precision,omitempty"`
+	Scale        *int64 `json:"scale,omitempty"`
+	Length       *int64 `json:"length,omitempty"`
+}
+
+// resultSet is a query result with column order and types preserved.
+type resultSet struct {
+	Columns []resultColumn
+	Rows    [][]any
+}
+
+// maps returns the rows keyed by column name (the pre-columns result shape).
+// Later duplicate column names overwrite earlier ones.
+func (r resultSet) maps() []map[string]any {
+	out := make([]map[string]any, 0, len(r.Rows))
+	for _, row := range r.Rows {
+		m := make(map[string]any, len(r.Columns))
+		for i, c := range r.Columns {
+			m[c.Name] = row[i]
 		}
-		s = strings.TrimSpace(s[end+2:])
-	}
-	switch {
-	case strings.HasPrefix(s, "select "):
-		return true
-	case strings.HasPrefix(s, "with "):
-		return true
-	case strings.HasPrefix(s, "show "):
-		return true
-	case strings.HasPrefix(s, "explain "):
-		return true
-	case s == "select" || s == "with" || s == "show" || s == "explain":
-		return true
-	default:
-		return false
+		out = append(out, m)
 	}
+	return out
 }
 
-func queryAll(ctx context.Context, db *sql.DB, query string, args ...any) ([]map[string]any, error) {
-	return queryAllLimited(ctx, db, query, 0, args...)
+func queryAll(ctx context.Context, db queryer, query string, args ...any) ([]map[string]any, error) {
+	out, _, err := queryPage(ctx, db, nil, query, 0, 0, args...)
+	return out.maps(), err
 }
 
-func queryAllLimited(ctx context.Context, db *sql.DB, query string, limit int, args ...any) ([]map[string]any, error) {
-	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
-	defer cancel()
+// errPageFull stops streamQuery once queryPage has a full page.
+var errPageFull = errors.New("page full")
 
+// queryPage discards the first skip rows and returns up to limit rows (all if
+// limit <= 0). more reports whether further rows were available. Values are
+// converted by enc, if non-nil.
+func queryPage(ctx context.Context, db queryer, enc valueEncoder, query string, skip, limit int, args ...any) (out resultSet, more bool, err error) {
+	out.Rows = make([][]any, 0)
+	err = streamQuery(ctx, db, enc, query, args,
+		func(cols []resultColumn) error {
+			out.Columns = cols
+			return nil
+		},
+		func(values []any) error {
+			if skip > 0 {
+				skip--
+				return nil
+			}
+			if limit > 0 && len(out.Rows) >= limit {
+				more = true
+				return errPageFull
+			}
+			out.Rows = append(out.Rows, values)
+			return nil
+		})
+	if err != nil && err != errPageFull {
+		return resultSet{}, false, err
+	}
+	return out, more, nil
+}
+
+// streamQuery runs query, passes the result columns to start and then every
+// row, converted by enc, to row. Each row gets a fresh slice. An error from a
+// callback stops the query and is returned as is.
+func streamQuery(ctx context.Context, db queryer, enc valueEncoder, query string, args []any, start func([]resultColumn) error, row func([]any) error) error {
 	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	cols, err := rows.Columns()
+	cols, err := resultColumns(rows)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if err := start(cols); err != nil {
+		return err
 	}
 
-	out := make([]map[string]any, 0)
+	encoders := columnEncoders(enc, cols)
+	progress := progressFromContext(ctx)
 	for rows.Next() {
-		if limit > 0 && len(out) >= limit {
-			break
-		}
 		values := make([]any, len(cols))
 		ptrs := make([]any, len(cols))
 		for i := range values {
 			ptrs[i] = &values[i]
 		}
 		if err := rows.Scan(ptrs...); err != nil {
-			return nil, err
+			return err
+		}
+		for i := range values {
+			values[i] = encoders[i](values[i])
 		}
-		m := make(map[string]any, len(cols))
-		for i, c := range cols {
-			m[c] = normalizeSQLValue(values[i])
+		if err := row(values); err != nil {
+			return err
 		}
-		out = append(out, m)
+		progress.addRow()
 	}
-	if err := rows.Err(); err != nil {
+	return rows.Err()
+}
+
+func resultColumns(rows *sql.Rows) ([]resultColumn, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
 		return nil, err
 	}
-	return out, nil
+	cols := make([]resultColumn, len(types))
+	for i, t := range types {
+		c := resultColumn{Name: t.Name(), DatabaseType: t.DatabaseTypeName()}
+		if nullable, ok := t.Nullable(); ok {
+			c.Nullable = &nullable
+		}
+		if precision, scale, ok := t.DecimalSize(); ok {
+			c.Precision, c.Scale = &precision, &scale
+		}
+		// Unbounded types (text, bytea) report MaxInt64.
+		if length, ok := t.Length(); ok && length != math.MaxInt64 {
+			c.Length = &length
+		}
+		cols[i] = c
+	}
+	return cols, nil
 }
 
 func normalizeSQLValue(v any) any {
@@ -96,3 +155,13 @@ func normalizeSQLValue(v any) any {
 		return x
 	}
 }
+
+// storedStatement formats a CREATE statement kept by the database (SQLite,
+// DuckDB) like the reconstructed ones: terminated by ";" and a newline.
+func storedStatement(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasSuffix(s, ";") {
+		s += ";"
+	}
+	return s + "\n"
+}
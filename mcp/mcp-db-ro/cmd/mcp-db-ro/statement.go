@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// cancelGrace is how much longer the client waits than the server-side
+// statement timeout, so the server's own timeout error normally wins.
+const cancelGrace = 2 * time.Second
+
+// statementController is implemented by drivers that can bound statement run
+// time on the server and cancel a running statement from another connection.
+type statementController interface {
+	// SessionID returns the server-side id of the pinned connection.
+	SessionID(ctx context.Context, conn *sql.Conn) (int64, error)
+	// SetStatementTimeout limits statements subsequently run on q. inTx
+	// reports whether q is a transaction (the setting is then transaction-local).
+	SetStatementTimeout(ctx context.Context, q queryer, timeout time.Duration, inTx bool) error
+	// CancelSession cancels the statement currently running in session id.
+	CancelSession(ctx context.Context, db *sql.DB, id int64) error
+}
+
+// readOnlyConnector is implemented by drivers whose connections are read-only
+// by construction. No transaction is started for them: the database refuses
+// writes on its own (and may not support transactions at all).
+type readOnlyConnector interface {
+	ReadOnlyConnection() bool
+}
+
+// roSession is a pinned connection running a read-only transaction (BEGIN ...
+// READ ONLY) with the connection's statement timeout applied server-side. The
+// transaction is always rolled back, so the database itself refuses writes even
+// if a query slips past checkReadOnlySQL.
+type roSession struct {
+	c         *dbClient
+	db        *sql.DB
+	conn      *sql.Conn
+	tx        *sql.Tx
+	q         queryer
+	inTx      bool
+	ctl       statementController
+	sessionID int64
+	timeout   time.Duration
+}
+
+// openReadOnly pins a connection from db and starts a read-only transaction on
+// it (unless the driver's connections are read-only anyway). If the
+// transaction cannot be started the call fails closed unless
+// allowNonReadOnlyTx is configured. Statements run on the session are bounded
+// by timeout. The session outlives ctx; call close.
+func (c *dbClient) openReadOnly(ctx context.Context, db *sql.DB, timeout time.Duration) (*roSession, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := &roSession{c: c, db: db, conn: conn, timeout: timeout}
+	s.ctl, _ = c.driver.(statementController)
+	if s.ctl != nil {
+		if s.sessionID, err = s.ctl.SessionID(ctx, conn); err != nil {
+			s.close()
+			return nil, fmt.Errorf("session id: %w", err)
+		}
+	}
+
+	if ro, ok := c.driver.(readOnlyConnector); ok && ro.ReadOnlyConnection() {
+		s.q = conn
+	} else {
+		// The transaction must not be tied to ctx: database/sql rolls it back
+		// when its context ends, and server-side cursors outlive a single call.
+		tx, err := conn.BeginTx(context.WithoutCancel(ctx), &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			if !c.cfg.AllowNonReadOnlyTx {
+				s.close()
+				return nil, fmt.Errorf("cannot start read-only transaction: %w (set allowNonReadOnlyTx to run without one)", err)
+			}
+			s.q = conn
+		} else {
+			s.tx, s.q, s.inTx = tx, tx, true
+		}
+	}
+
+	if s.ctl != nil {
+		if err := s.ctl.SetStatementTimeout(ctx, s.q, timeout, s.inTx); err != nil {
+			s.close()
+			return nil, fmt.Errorf("set statement timeout: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// run calls fn on the session. If ctx ends while fn is running, the statement
+// is cancelled on the server as well.
+func (s *roSession) run(ctx context.Context, fn func(context.Context, queryer) error) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout+cancelGrace)
+	defer cancel()
+
+	if s.ctl != nil {
+		done := make(chan struct{})
+		stop := context.AfterFunc(ctx, func() {
+			defer close(done)
+			cctx, ccancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer ccancel()
+			if err := s.ctl.CancelSession(cctx, s.db, s.sessionID); err != nil {
+				s.c.logf("connection %s: cancel session %d: %v", s.c.cfg.Name, s.sessionID, err)
+			}
+		})
+		defer func() {
+			if !stop() {
+				<-done
+			}
+		}()
+	}
+	return fn(ctx, s.q)
+}
+
+func (s *roSession) close() {
+	if s.tx != nil {
+		_ = s.tx.Rollback()
+	}
+	_ = s.conn.Close()
+}
+
+// readOnly runs fn once inside a fresh read-only session on db, bounded by the
+// connection's query timeout. The returned bool reports whether a read-only
+// transaction was used.
+func (c *dbClient) readOnly(ctx context.Context, db *sql.DB, fn func(context.Context, queryer) error) (bool, error) {
+	return c.readOnlyWithin(ctx, db, c.cfg.queryTimeout(), fn)
+}
+
+// readOnlyWithin is readOnly with an explicit statement timeout.
+func (c *dbClient) readOnlyWithin(ctx context.Context, db *sql.DB, timeout time.Duration, fn func(context.Context, queryer) error) (bool, error) {
+	s, err := c.openReadOnly(ctx, db, timeout)
+	if err != nil {
+		return false, err
+	}
+	defer s.close()
+	return s.inTx, s.run(ctx, fn)
+}
+
+func (c *dbClient) logf(format string, args ...any) {
+	if c.logger != nil {
+		c.logger.Printf(format, args...)
+	}
+}
+
+// withQueryTimeout bounds metadata calls by the connection's query timeout.
+func (c *dbClient) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, c.cfg.queryTimeout())
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// tidbDriver is mysqlDriver for TiDB, whose EXPLAIN formats differ and whose
+// information_schema.partitions also lists unpartitioned tables.
+type tidbDriver struct{ mysqlDriver }
+
+// TablePartitions lists partitions with their TiDB partition ids and
+// placement policies. TiDB has no subpartitions, and row counts and sizes are
+// statistics estimates.
+func (tidbDriver) TablePartitions(ctx context.Context, db *sql.DB, ref TableRef) ([]map[string]any, error) {
+	return queryAll(ctx, db, `
+SELECT
+  partition_name,
+  partition_ordinal_position,
+  partition_method,
+  partition_expression,
+  partition_description,
+  table_rows,
+  data_length,
+  index_length,
+  tidb_partition_id,
+  tidb_placement_policy_name
+FROM information_schema.partitions
+WHERE table_schema = ? AND table_name = ? AND partition_name IS NOT NULL
+ORDER BY partition_ordinal_position`, ref.Database, ref.Table)
+}
+
+func (tidbDriver) Explain(ctx context.Context, db queryer, query, format string, args ...any) ([]map[string]any, error) {
+	switch f := strings.ToLower(strings.TrimSpace(format)); f {
+	case "", "text", "row":
+		return queryAll(ctx, db, "EXPLAIN "+query, args...)
+	case "brief", "dot":
+		return queryAll(ctx, db, "EXPLAIN FORMAT = '"+f+"' "+query, args...)
+	case "json":
+		return queryAll(ctx, db, "EXPLAIN FORMAT = 'tidb_json' "+query, args...)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s (tidb supports: text, brief, dot, json)", format)
+	}
+}
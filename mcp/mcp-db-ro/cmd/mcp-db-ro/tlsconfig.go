@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// certExpiryWarning is how close to expiry a certificate is flagged in
+// db.listConnections.
+const certExpiryWarning = 30 * 24 * time.Hour
+
+// TLSConfig configures TLS for postgres and mysql connections. In the config
+// it is either an object or, as before, just a go-sql-driver/mysql TLS mode
+// ("true", "skip-verify", "preferred", "false").
+type TLSConfig struct {
+	// Mode is the MySQL TLS mode; Postgres uses sslMode.
+	Mode string `json:"mode,omitempty"`
+	// CAFile is a PEM bundle of the CAs to verify the server with; CertFile
+	// and KeyFile are the client certificate and its key.
+	CAFile   string `json:"caFile,omitempty"`
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+	// ServerName is verified instead of the host name.
+	ServerName string `json:"serverName,omitempty"`
+	// MinVersion is "1.2" or "1.3" (older versions are accepted but
+	// discouraged); the Go default otherwise.
+	MinVersion string `json:"minVersion,omitempty"`
+	// Fingerprint pins the server certificate by the SHA-256 of its DER
+	// encoding, in hex (colons allowed).
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+func (t *TLSConfig) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		*t = TLSConfig{}
+		return json.Unmarshal(b, &t.Mode)
+	}
+	type plain TLSConfig
+	return json.Unmarshal(b, (*plain)(t))
+}
+
+// custom reports whether t sets anything beyond the mode, which the server
+// then has to turn into a tls.Config itself.
+func (t TLSConfig) custom() bool {
+	return t.CAFile != "" || t.CertFile != "" || t.KeyFile != "" ||
+		t.ServerName != "" || t.MinVersion != "" || t.Fingerprint != ""
+}
+
+// tlsState is the loaded TLS settings of a connection and what was seen of
+// the server's certificate.
+type tlsState struct {
+	mode       string
+	serverName string
+	minVersion uint16
+	roots      *x509.CertPool
+	certs      []tls.Certificate
+	pin        []byte
+
+	caExpires   time.Time
+	certExpires time.Time
+
+	mu            sync.Mutex
+	serverExpires time.Time
+	serverSubject string
+}
+
+// loadTLS reads the files of t and validates its settings.
+func loadTLS(t TLSConfig) (*tlsState, error) {
+	st := &tlsState{mode: strings.ToLower(strings.TrimSpace(t.Mode)), serverName: t.ServerName}
+	switch st.mode {
+	case "", "true", "skip-verify", "preferred":
+	case "false":
+		return nil, errors.New("tls.mode false turns TLS off; remove the other tls settings")
+	default:
+		return nil, fmt.Errorf("unsupported tls.mode %q (use true, skip-verify or preferred)", t.Mode)
+	}
+	if t.CAFile != "" {
+		b, err := os.ReadFile(expandHome(t.CAFile))
+		if err != nil {
+			return nil, fmt.Errorf("tls.caFile: %w", err)
+		}
+		st.roots = x509.NewCertPool()
+		for rest := b; ; {
+			var block *pem.Block
+			if block, rest = pem.Decode(rest); block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("tls.caFile %s: %w", t.CAFile, err)
+			}
+			st.roots.AddCert(cert)
+			if st.caExpires.IsZero() || cert.NotAfter.Before(st.caExpires) {
+				st.caExpires = cert.NotAfter
+			}
+		}
+		if st.caExpires.IsZero() {
+			return nil, fmt.Errorf("tls.caFile %s: no PEM certificates found", t.CAFile)
+		}
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return nil, errors.New("tls.certFile and tls.keyFile must be set together")
+	}
+	if t.CertFile != "" {
+		pair, err := tls.LoadX509KeyPair(expandHome(t.CertFile), expandHome(t.KeyFile))
+		if err != nil {
+			return nil, fmt.Errorf("tls.certFile: %w", err)
+		}
+		st.certs = []tls.Certificate{pair}
+		if pair.Leaf != nil {
+			st.certExpires = pair.Leaf.NotAfter
+		}
+	}
+	switch t.MinVersion {
+	case "":
+	case "1.0":
+		st.minVersion = tls.VersionTLS10
+	case "1.1":
+		st.minVersion = tls.VersionTLS11
+	case "1.2":
+		st.minVersion = tls.VersionTLS12
+	case "1.3":
+		st.minVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported tls.minVersion %q (use 1.2 or 1.3)", t.MinVersion)
+	}
+	if t.Fingerprint != "" {
+		pin, err := hex.DecodeString(strings.ReplaceAll(strings.TrimSpace(t.Fingerprint), ":", ""))
+		if err != nil || len(pin) != sha256.Size {
+			return nil, errors.New("tls.fingerprint must be a hex SHA-256 digest")
+		}
+		st.pin = pin
+		if st.mode == "preferred" {
+			return nil, errors.New("tls.fingerprint cannot be combined with tls.mode preferred")
+		}
+	}
+	return st, nil
+}
+
+// clientConfig returns a tls.Config for go-sql-driver/mysql. With
+// skip-verify a fingerprint, if set, is still checked.
+func (st *tlsState) clientConfig() *tls.Config {
+	c := &tls.Config{
+		RootCAs:            st.roots,
+		Certificates:       st.certs,
+		InsecureSkipVerify: st.mode == "skip-verify" || st.mode == "preferred",
+	}
+	st.apply(c)
+	return c
+}
+
+// apply adds the server name, minimum version and fingerprint check to a
+// tls.Config built by a driver, and records the server certificate.
+func (st *tlsState) apply(c *tls.Config) {
+	if st.serverName != "" {
+		c.ServerName = st.serverName
+	}
+	if st.minVersion != 0 {
+		c.MinVersion = st.minVersion
+	}
+	c.VerifyConnection = st.verifyConnection
+}
+
+func (st *tlsState) verifyConnection(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("tls: server sent no certificate")
+	}
+	leaf := cs.PeerCertificates[0]
+	st.mu.Lock()
+	st.serverExpires = leaf.NotAfter
+	st.serverSubject = leaf.Subject.String()
+	st.mu.Unlock()
+	if st.pin != nil {
+		sum := sha256.Sum256(leaf.Raw)
+		if !bytes.Equal(sum[:], st.pin) {
+			return fmt.Errorf("tls: server certificate fingerprint %s does not match the pinned one", hex.EncodeToString(sum[:]))
+		}
+	}
+	return nil
+}
+
+// status reports certificate expiry for db.listConnections. The server
+// certificate is known once a connection has been made.
+func (st *tlsState) status() map[string]any {
+	out := map[string]any{}
+	soon := false
+	add := func(key string, t time.Time) {
+		if t.IsZero() {
+			return
+		}
+		out[key] = t.UTC().Format(time.RFC3339)
+		if time.Until(t) < certExpiryWarning {
+			soon = true
+		}
+	}
+	add("caExpires", st.caExpires)
+	add("clientCertExpires", st.certExpires)
+	st.mu.Lock()
+	add("serverCertExpires", st.serverExpires)
+	if st.serverSubject != "" {
+		out["serverCertSubject"] = st.serverSubject
+	}
+	st.mu.Unlock()
+	if st.pin != nil {
+		out["pinned"] = true
+	}
+	if soon {
+		out["expiresSoon"] = true
+	}
+	return out
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	sshDialTimeout       = 10 * time.Second
+	sshKeepaliveInterval = 30 * time.Second
+)
+
+// dialFunc dials the database server on behalf of a driver.
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// sshTunnel carries a connection's database traffic through an SSH server.
+// The SSH connection is opened on first use and re-opened when it drops;
+// every database connection is a channel on it.
+type sshTunnel struct {
+	name   string
+	addr   string
+	config *ssh.ClientConfig
+	// agentSock is the ssh-agent socket, dialed afresh for every handshake so
+	// that a restarted agent is picked up.
+	agentSock string
+	logger    *log.Logger
+
+	mu         sync.Mutex
+	client     *ssh.Client
+	closed     bool
+	connected  bool // the tunnel has been up at least once
+	since      time.Time
+	lastErr    error
+	reconnects int
+}
+
+// newSSHTunnel validates cfg and prepares the tunnel without connecting.
+func newSSHTunnel(logger *log.Logger, name string, cfg SSHConfig) (*sshTunnel, error) {
+	host := strings.TrimSpace(cfg.Host)
+	user := strings.TrimSpace(cfg.User)
+	if user == "" {
+		return nil, errors.New("ssh.user is required")
+	}
+	if cfg.KeyFile == "" && !cfg.Agent {
+		return nil, errors.New("ssh needs keyFile or agent")
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = 22
+	}
+	t := &sshTunnel{
+		name:   name,
+		addr:   net.JoinHostPort(host, strconv.Itoa(port)),
+		logger: logger,
+	}
+
+	var auth []ssh.AuthMethod
+	if cfg.KeyFile != "" {
+		signer, err := readSSHKey(cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if cfg.Agent {
+		sock := os.Getenv("SSH_AUTH_SOCK")
+		if sock == "" {
+			return nil, errors.New("ssh.agent is set but SSH_AUTH_SOCK is not")
+		}
+		t.agentSock = sock
+	}
+
+	knownHostsFile := cfg.KnownHosts
+	if knownHostsFile == "" {
+		knownHostsFile = "~/.ssh/known_hosts"
+	}
+	hostKeys, err := knownhosts.New(expandHome(knownHostsFile))
+	if err != nil {
+		return nil, fmt.Errorf("ssh.knownHosts: %w", err)
+	}
+	t.config = &ssh.ClientConfig{
+		User:              user,
+		Auth:              auth,
+		HostKeyCallback:   hostKeys,
+		HostKeyAlgorithms: knownHostKeyAlgorithms(hostKeys, t.addr),
+		Timeout:           sshDialTimeout,
+	}
+	return t, nil
+}
+
+// readSSHKey loads an unencrypted private key. Keys with a passphrase are
+// meant to be used through ssh-agent.
+func readSSHKey(path string) (ssh.Signer, error) {
+	b, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("ssh.keyFile: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(b)
+	if err != nil {
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			return nil, fmt.Errorf("ssh.keyFile %s is protected by a passphrase (add it to ssh-agent and set ssh.agent instead)", path)
+		}
+		return nil, fmt.Errorf("ssh.keyFile %s: %w", path, err)
+	}
+	return signer, nil
+}
+
+// knownHostKeyAlgorithms returns the key algorithms known_hosts lists for
+// addr, so the server is asked for a key that can be verified rather than its
+// preferred one. It returns nil (the defaults) for unknown hosts.
+func knownHostKeyAlgorithms(hostKeys ssh.HostKeyCallback, addr string) []string {
+	probe, err := ssh.NewPublicKey(ed25519.PublicKey(make([]byte, ed25519.PublicKeySize)))
+	if err != nil {
+		return nil
+	}
+	var keyErr *knownhosts.KeyError
+	if !errors.As(hostKeys(addr, &net.TCPAddr{}, probe), &keyErr) {
+		return nil
+	}
+	var algos []string
+	for _, k := range keyErr.Want {
+		switch typ := k.Key.Type(); typ {
+		case ssh.KeyAlgoRSA:
+			algos = append(algos, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA)
+		default:
+			algos = append(algos, typ)
+		}
+	}
+	return algos
+}
+
+// dial opens a channel to addr through the tunnel, connecting or
+// reconnecting the SSH session as needed.
+func (t *sshTunnel) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, err := t.connect()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := client.DialContext(ctx, network, addr)
+	if err == nil {
+		return deadlineConn(conn), nil
+	}
+	// Tell a refused forward apart from a dead session, which is replaced
+	// once.
+	if _, _, kerr := client.SendRequest("keepalive@openssh.com", true, nil); kerr == nil {
+		return nil, fmt.Errorf("ssh tunnel: %w", err)
+	}
+	t.drop(client, err)
+	if client, err = t.connect(); err != nil {
+		return nil, err
+	}
+	if conn, err = client.DialContext(ctx, network, addr); err != nil {
+		return nil, fmt.Errorf("ssh tunnel: %w", err)
+	}
+	return deadlineConn(conn), nil
+}
+
+// deadlineConn bridges an SSH channel through net.Pipe. The channel does not
+// implement deadlines, which the drivers rely on to time out and cancel
+// statements; the pipe does.
+func deadlineConn(ch net.Conn) net.Conn {
+	local, remote := net.Pipe()
+	go func() {
+		_, _ = io.Copy(remote, ch)
+		_ = remote.Close()
+	}()
+	go func() {
+		_, _ = io.Copy(ch, remote)
+		_ = ch.Close()
+	}()
+	return local
+}
+
+// connect returns the live SSH client, opening a new one if there is none.
+// The handshake runs without t.mu held, so a slow server does not block
+// status or close; if several callers connect at once, the first to finish
+// wins and the others' clients are discarded.
+func (t *sshTunnel) connect() (*ssh.Client, error) {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil, errors.New("ssh tunnel closed")
+	}
+	if client := t.client; client != nil {
+		t.mu.Unlock()
+		return client, nil
+	}
+	config := *t.config
+	t.mu.Unlock()
+
+	client, err := t.handshake(&config)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		if client != nil {
+			_ = client.Close()
+		}
+		return nil, errors.New("ssh tunnel closed")
+	}
+	if t.client != nil {
+		if client != nil {
+			_ = client.Close()
+		}
+		return t.client, nil
+	}
+	if err != nil {
+		t.lastErr = err
+		return nil, fmt.Errorf("ssh %s: %w", t.addr, err)
+	}
+	if t.connected {
+		t.reconnects++
+		if t.logger != nil {
+			t.logger.Printf("connection %s: ssh tunnel to %s re-established", t.name, t.addr)
+		}
+	}
+	t.client, t.connected, t.since, t.lastErr = client, true, time.Now(), nil
+	go t.monitor(client)
+	return client, nil
+}
+
+// handshake opens a new SSH client with config, authenticating through a
+// fresh ssh-agent connection if one is configured. The agent connection is
+// only needed for the handshake and is closed when it ends.
+func (t *sshTunnel) handshake(config *ssh.ClientConfig) (*ssh.Client, error) {
+	if t.agentSock != "" {
+		conn, err := net.Dial("unix", t.agentSock)
+		if err != nil {
+			return nil, fmt.Errorf("ssh agent: %w", err)
+		}
+		defer conn.Close()
+		config.Auth = append(slices.Clip(config.Auth), ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+	}
+	return ssh.Dial("tcp", t.addr, config)
+}
+
+// monitor sends keepalives on client and marks the tunnel down when the
+// session ends, so the next dial reconnects.
+func (t *sshTunnel) monitor(client *ssh.Client) {
+	done := make(chan error, 1)
+	go func() { done <- client.Wait() }()
+	ticker := time.NewTicker(sshKeepaliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			if err == nil {
+				err = errors.New("session closed")
+			}
+			t.drop(client, err)
+			return
+		case <-ticker.C:
+			if _, _, err := client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+				t.drop(client, err)
+			}
+		}
+	}
+}
+
+// drop forgets client after a failure unless it was already replaced.
+func (t *sshTunnel) drop(client *ssh.Client, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client != client {
+		return
+	}
+	t.client, t.since, t.lastErr = nil, time.Now(), err
+	_ = client.Close()
+	if !t.closed && t.logger != nil {
+		t.logger.Printf("connection %s: ssh tunnel to %s lost: %v", t.name, t.addr, err)
+	}
+}
+
+// status reports the tunnel for db.listConnections: state is "up", "down"
+// (it failed or dropped and will be re-opened on the next query) or "idle"
+// (not opened yet).
+func (t *sshTunnel) status() map[string]any {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state := "idle"
+	switch {
+	case t.client != nil:
+		state = "up"
+	case t.connected || t.lastErr != nil:
+		state = "down"
+	}
+	out := map[string]any{"host": t.addr, "state": state, "reconnects": t.reconnects}
+	if !t.since.IsZero() {
+		out["since"] = t.since.UTC().Format(time.RFC3339)
+	}
+	if t.lastErr != nil {
+		out["lastError"] = t.lastErr.Error()
+	}
+	return out
+}
+
+func (t *sshTunnel) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	if t.client != nil {
+		_ = t.client.Close()
+		t.client = nil
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+)
+
+// yugabyteDriver is postgresDriver for YugabyteDB. Tables are split into
+// tablets regardless of declarative partitioning, which pg_inherits does not
+// show.
+type yugabyteDriver struct{ postgresDriver }
+
+// TablePartitions lists declarative partitions like Postgres; for a table
+// without them it reports its tablets from yb_table_properties() instead.
+func (d yugabyteDriver) TablePartitions(ctx context.Context, db *sql.DB, ref TableRef) ([]map[string]any, error) {
+	parts, err := d.postgresDriver.TablePartitions(ctx, db, ref)
+	if err != nil || len(parts) > 0 {
+		return parts, err
+	}
+	return queryAll(ctx, db, `
+SELECT $2 AS table_name, num_tablets, num_hash_key_columns, is_colocated
+FROM yb_table_properties($1::regclass)`, quoteIdentPG(ref.Schema)+"."+quoteIdentPG(ref.Table), ref.Table)
+}